@@ -0,0 +1,99 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"fmt"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// ApplianceRole クラスタ内でのアプライアンスの役割
+type ApplianceRole string
+
+const (
+	// ApplianceRolePrimary DatabaseAPI.Createで作成したプライマリのアプライアンス
+	ApplianceRolePrimary ApplianceRole = "primary"
+	// ApplianceRoleAddNodes InstanceAPI.AddNodesで追加したアプライアンス
+	ApplianceRoleAddNodes ApplianceRole = "add_nodes"
+)
+
+// ApplianceDescriptor DescribeApplianceの結果
+type ApplianceDescriptor struct {
+	ID   string
+	Plan Plan
+	Role ApplianceRole
+	// ParentID 追加先のプライマリのアプライアンスID。プライマリの場合は空
+	ParentID string
+	// Zone アプライアンスのゾーン名
+	Zone string
+}
+
+// DescribeAppliance ReadやListで取得したアプライアンスのプランと役割を判別する。
+// プランはPlan.ID、ServiceClass、Remark.ServiceClassの順に判別し、これらが矛盾する場合はエラーを返す
+func DescribeAppliance(appliance *v1.GetNosqlAppliance) (*ApplianceDescriptor, error) {
+	var (
+		found    bool
+		spec     PlanSpec
+		forNodes bool
+	)
+	check := func(source string, s PlanSpec, n bool, ok bool) error {
+		if !ok {
+			return nil
+		}
+		if found && (s.Plan != spec.Plan || n != forNodes) {
+			return NewError(fmt.Sprintf("appliance %s has inconsistent plan: %s indicates %s", appliance.ID.Value, source, describePlan(s.Plan, n)), nil)
+		}
+		found, spec, forNodes = true, s, n
+		return nil
+	}
+
+	if id, ok := appliance.Plan.Value.ID.Get(); ok {
+		s, n, ok := GetPlanSpecFromID(id)
+		if err := check("Plan.ID", s, n, ok); err != nil {
+			return nil, err
+		}
+	}
+	if sc, ok := appliance.ServiceClass.Get(); ok {
+		s, n, ok := GetPlanSpecFromServiceClass(string(sc))
+		if err := check("ServiceClass", s, n, ok); err != nil {
+			return nil, err
+		}
+	}
+	remark := appliance.Remark.Value
+	if sc, ok := remark.ServiceClass.Get(); ok {
+		s, n, ok := GetPlanSpecFromServiceClass(sc)
+		if err := check("Remark.ServiceClass", s, n, ok); err != nil {
+			return nil, err
+		}
+	}
+	if !found {
+		return nil, NewError(fmt.Sprintf("unable to determine plan of appliance %s", appliance.ID.Value), nil)
+	}
+
+	ret := &ApplianceDescriptor{
+		ID:   appliance.ID.Value,
+		Plan: spec.Plan,
+		Role: ApplianceRolePrimary,
+		Zone: remark.Nosql.Value.Zone.Value,
+	}
+	parent := remark.Nosql.Value.PrimaryNodes.Value.Appliance.Value
+	if parentID, ok := parent.ID.Get(); ok && parentID != "" {
+		ret.Role = ApplianceRoleAddNodes
+		ret.ParentID = parentID
+		if ret.Zone == "" {
+			ret.Zone = parent.Zone.Value.Name.Value
+		}
+	} else if forNodes {
+		return nil, NewError(fmt.Sprintf("appliance %s has an add-nodes plan but no primary appliance", appliance.ID.Value), nil)
+	}
+	return ret, nil
+}
+
+func describePlan(p Plan, forNodes bool) string {
+	if forNodes {
+		return string(p) + " (nodes)"
+	}
+	return string(p)
+}
@@ -0,0 +1,62 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"testing"
+
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeAppliance(t *testing.T) {
+	cases := []struct {
+		name    string
+		json    string
+		want    *ApplianceDescriptor
+		wantErr bool
+	}{
+		{
+			name: "primary",
+			json: `{"ID":"123","Plan":{"ID":51144},"ServiceClass":"cloud/nosql/plan/3","Remark":{"ServiceClass":"cloud/nosql/plan/3","Nosql":{"Zone":"tk1b"}}}`,
+			want: &ApplianceDescriptor{ID: "123", Plan: Plan250GB, Role: ApplianceRolePrimary, Zone: "tk1b"},
+		},
+		{
+			name: "add nodes",
+			json: `{"ID":"456","Plan":{"ID":51145},"ServiceClass":"cloud/nosql/plan/2/node","Remark":{"Nosql":{"PrimaryNodes":{"Appliance":{"ID":"123","Zone":{"Name":"is1b"}}}}}}`,
+			want: &ApplianceDescriptor{ID: "456", Plan: Plan100GB, Role: ApplianceRoleAddNodes, ParentID: "123", Zone: "is1b"},
+		},
+		{
+			name: "service class only",
+			json: `{"ID":"789","Remark":{"ServiceClass":"cloud/nosql/plan/1","Nosql":{"Zone":"tk1b"}}}`,
+			want: &ApplianceDescriptor{ID: "789", Plan: Plan40GB, Role: ApplianceRolePrimary, Zone: "tk1b"},
+		},
+		{
+			name:    "inconsistent",
+			json:    `{"ID":"123","Plan":{"ID":51143},"ServiceClass":"cloud/nosql/plan/3"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown",
+			json:    `{"ID":"123"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var appliance v1.GetNosqlAppliance
+			require.NoError(t, appliance.UnmarshalJSON([]byte(tc.json)))
+
+			got, err := DescribeAppliance(&appliance)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
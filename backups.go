@@ -6,6 +6,8 @@ package nosql
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	v1 "github.com/sacloud/nosql-api-go/apis/v1"
@@ -16,101 +18,275 @@ type BackupAPI interface {
 	Create(ctx context.Context) error
 	Restore(ctx context.Context, id uuid.UUID) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	CreateAndWait(ctx context.Context, opts *WaitOptions) (*v1.NosqlBackup, error)
+	RestoreAndWait(ctx context.Context, id uuid.UUID, opts *WaitOptions) (*RestoreOutcome, error)
+}
+
+const (
+	// RestoreStatusNotRestored 未復元を示すNosqlBackup.RestoreStatusの値
+	RestoreStatusNotRestored = "0"
+	// RestoreStatusRestoring 復元中を示すNosqlBackup.RestoreStatusの値
+	RestoreStatusRestoring = "1"
+	// RestoreStatusRestored 復元完了を示すNosqlBackup.RestoreStatusの値
+	RestoreStatusRestored = "2"
+	// RestoreStatusFailed 復元失敗を示すNosqlBackup.RestoreStatusの値
+	RestoreStatusFailed = "9"
+)
+
+// RestoreResult RestoreAndWaitの結果の種別
+type RestoreResult string
+
+const (
+	RestoreSucceeded RestoreResult = "success"
+	RestoreFailed    RestoreResult = "failed"
+	RestoreTimedOut  RestoreResult = "timed_out"
+)
+
+// RestoreOutcome RestoreAndWaitの結果
+type RestoreOutcome struct {
+	Result RestoreResult
+	// Backup 最後に観測したバックアップ
+	Backup *v1.NosqlBackup
+	// Availability 最後に観測したアプライアンスのAvailability
+	Availability v1.Availability
+}
+
+// RestoreFailedError バックアップからの復元が失敗したことを示すエラー
+type RestoreFailedError struct {
+	BackupID     uuid.UUID
+	Availability v1.Availability
+}
+
+func (e *RestoreFailedError) Error() string {
+	return fmt.Sprintf("restore from backup %s failed (availability: %s)", e.BackupID, e.Availability)
 }
 
 var _ BackupAPI = (*backupOp)(nil)
 
 type backupOp struct {
-	client *v1.Client
+	client *Client
 	dbId   string
+	opConfig
 }
 
-func NewBackupOp(client *v1.Client, dbId string) BackupAPI {
-	return &backupOp{client: client, dbId: dbId}
+func NewBackupOp(client *Client, dbId string, opts ...OpOption) BackupAPI {
+	return &backupOp{client: client, dbId: dbId, opConfig: newOpConfig(opts)}
 }
 
 func (op *backupOp) List(ctx context.Context) ([]v1.NosqlBackup, error) {
-	res, err := op.client.GetBackupByApplianceID(ctx, v1.GetBackupByApplianceIDParams{ApplianceID: op.dbId})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.GetBackupByApplianceIDOperation, func(ctx context.Context) (v1.GetBackupByApplianceIDRes, error) {
+		return op.client.GetBackupByApplianceID(ctx, v1.GetBackupByApplianceIDParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return nil, NewAPIError("Backup.List", 0, err)
+		return nil, newAPIError(ctx, "Backup.List", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlBackupResponse:
 		return p.Nosql.Value.Backups, nil
 	case *v1.BadRequestResponse:
-		return nil, NewAPIError("Backup.List", 400, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Backup.List", 400, p)
 	case *v1.UnauthorizedResponse:
-		return nil, NewAPIError("Backup.List", 401, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Backup.List", 401, p)
 	case *v1.ServerErrorResponse:
-		return nil, NewAPIError("Backup.List", 500, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Backup.List", 500, p)
 	default:
-		return nil, NewAPIError("Backup.List", 0, nil)
+		return nil, newAPIError(ctx, "Backup.List", 0, nil)
 	}
 }
 
 func (op *backupOp) Create(ctx context.Context) error {
-	res, err := op.client.CreateBackup(ctx, v1.CreateBackupParams{ApplianceID: op.dbId})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.CreateBackupOperation, func(ctx context.Context) (v1.CreateBackupRes, error) {
+		return op.client.CreateBackup(ctx, v1.CreateBackupParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return NewAPIError("Backup.Create", 0, err)
+		return newAPIError(ctx, "Backup.Create", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlOkResponse:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Backup.Create", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Create", 400, p)
 	case *v1.UnauthorizedResponse:
-		return NewAPIError("Backup.Create", 401, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Create", 401, p)
 	case *v1.NotFoundResponse:
-		return NewAPIError("Backup.Create", 404, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Create", 404, p)
 	case *v1.ServerErrorResponse:
-		return NewAPIError("Backup.Create", 500, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Create", 500, p)
 	default:
-		return NewAPIError("Backup.Create", 0, nil)
+		return newAPIError(ctx, "Backup.Create", 0, nil)
 	}
 }
 
 func (op *backupOp) Restore(ctx context.Context, id uuid.UUID) error {
-	res, err := op.client.RestoreBackup(ctx, v1.RestoreBackupParams{ApplianceID: op.dbId, BackupID: id})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.RestoreBackupOperation, func(ctx context.Context) (v1.RestoreBackupRes, error) {
+		return op.client.RestoreBackup(ctx, v1.RestoreBackupParams{ApplianceID: op.dbId, BackupID: id})
+	})
 	if err != nil {
-		return NewAPIError("Backup.Restore", 0, err)
+		return newAPIError(ctx, "Backup.Restore", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlOkResponse:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Backup.Restore", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Restore", 400, p)
 	case *v1.UnauthorizedResponse:
-		return NewAPIError("Backup.Restore", 401, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Restore", 401, p)
 	case *v1.NotFoundResponse:
-		return NewAPIError("Backup.Restore", 404, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Restore", 404, p)
 	case *v1.ServerErrorResponse:
-		return NewAPIError("Backup.Restore", 500, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Restore", 500, p)
 	default:
-		return NewAPIError("Backup.Restore", 0, nil)
+		return newAPIError(ctx, "Backup.Restore", 0, nil)
 	}
 }
 
 func (op *backupOp) Delete(ctx context.Context, id uuid.UUID) error {
-	res, err := op.client.DeleteBackup(ctx, v1.DeleteBackupParams{ApplianceID: op.dbId, BackupID: id})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.DeleteBackupOperation, func(ctx context.Context) (v1.DeleteBackupRes, error) {
+		return op.client.DeleteBackup(ctx, v1.DeleteBackupParams{ApplianceID: op.dbId, BackupID: id})
+	})
 	if err != nil {
-		return NewAPIError("Backup.Delete", 0, err)
+		return newAPIError(ctx, "Backup.Delete", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlOkResponse:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Backup.Delete", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Delete", 400, p)
 	case *v1.UnauthorizedResponse:
-		return NewAPIError("Backup.Delete", 401, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Delete", 401, p)
 	case *v1.NotFoundResponse:
-		return NewAPIError("Backup.Delete", 404, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Delete", 404, p)
 	case *v1.ServerErrorResponse:
-		return NewAPIError("Backup.Delete", 500, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Backup.Delete", 500, p)
 	default:
-		return NewAPIError("Backup.Delete", 0, nil)
+		return newAPIError(ctx, "Backup.Delete", 0, nil)
+	}
+}
+
+// CreateAndWait バックアップを作成し、作成されたバックアップが一覧に現れるまで待機する
+func (op *backupOp) CreateAndWait(ctx context.Context, opts *WaitOptions) (_ *v1.NosqlBackup, err error) {
+	ctx, span := startSpan(ctx, op.client, "Backup.CreateAndWait", op.dbId)
+	defer func() { endSpan(span, err) }()
+
+	before, err := op.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[uuid.UUID]struct{}, len(before))
+	var latest time.Time
+	for _, b := range before {
+		known[b.BackupId] = struct{}{}
+		if b.BackupAt.After(latest) {
+			latest = b.BackupAt
+		}
+	}
+
+	if err := op.Create(ctx); err != nil {
+		return nil, err
+	}
+
+	var created *v1.NosqlBackup
+	err = poll(ctx, metricsOf(op.client), "Backup.CreateAndWait", opts, func(ctx context.Context) (string, bool, error) {
+		backups, err := op.List(ctx)
+		if err != nil {
+			return "", false, err
+		}
+		for i := range backups {
+			b := &backups[i]
+			if _, ok := known[b.BackupId]; ok || !b.BackupAt.After(latest) {
+				continue
+			}
+			if created == nil || b.BackupAt.After(created.BackupAt) {
+				created = b
+			}
+		}
+		if created == nil {
+			return fmt.Sprintf("%d backups", len(backups)), false, nil
+		}
+		return created.BackupId.String(), true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// RestoreAndWait バックアップから復元し、RestoreStatusが終了状態かつアプライアンスがavailableになるまで待機する。
+// 失敗・タイムアウトの場合もRestoreOutcomeを返し、あわせてエラーを返す
+func (op *backupOp) RestoreAndWait(ctx context.Context, id uuid.UUID, opts *WaitOptions) (_ *RestoreOutcome, err error) {
+	ctx, span := startSpan(ctx, op.client, "Backup.RestoreAndWait", op.dbId)
+	defer func() { endSpan(span, err) }()
+
+	before, err := op.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := op.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+
+	databaseOp := NewDatabaseOp(op.client, op.options()...)
+	outcome := &RestoreOutcome{Backup: before}
+	err = poll(ctx, metricsOf(op.client), "Backup.RestoreAndWait", opts, func(ctx context.Context) (string, bool, error) {
+		backup, err := op.find(ctx, id)
+		if err != nil {
+			return "", false, err
+		}
+		outcome.Backup = backup
+		appliance, err := databaseOp.Read(ctx, op.dbId)
+		if err != nil {
+			return "", false, err
+		}
+		outcome.Availability = appliance.Availability.Value
+
+		restoreStatus := backup.RestoreStatus.Value
+		state := fmt.Sprintf("restore=%s availability=%s", restoreStatus, outcome.Availability)
+		// 以前の復元で既に同じ終了状態になっている場合はRestoreAtが更新されるまで待つ
+		reached := func(status string) bool {
+			return restoreStatus == status &&
+				(before.RestoreStatus.Value != status || backup.RestoreAt.Value.After(before.RestoreAt.Value))
+		}
+		if reached(RestoreStatusFailed) || outcome.Availability == v1.AvailabilityFailed {
+			outcome.Result = RestoreFailed
+			return state, true, nil
+		}
+		if reached(RestoreStatusRestored) && outcome.Availability == v1.AvailabilityAvailable {
+			outcome.Result = RestoreSucceeded
+			return state, true, nil
+		}
+		return state, false, nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrWaitTimeout) {
+			outcome.Result = RestoreTimedOut
+			return outcome, err
+		}
+		return nil, err
+	}
+	if outcome.Result == RestoreFailed {
+		return outcome, NewError("Backup.RestoreAndWait", &RestoreFailedError{BackupID: id, Availability: outcome.Availability})
+	}
+	return outcome, nil
+}
+
+func (op *backupOp) find(ctx context.Context, id uuid.UUID) (*v1.NosqlBackup, error) {
+	backups, err := op.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range backups {
+		if backups[i].BackupId == id {
+			return &backups[i], nil
+		}
 	}
+	return nil, NewError("Backup.RestoreAndWait", fmt.Errorf("backup %s not found", id))
 }
@@ -0,0 +1,135 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	backupOld = `{"backupId":"6e2b4c3a-0000-4000-8000-000000000001","backupDestination":"nfs://192.168.0.31/export","backupAt":"2025-01-01T00:00:00+09:00","size":100}`
+	backupNew = `{"backupId":"6e2b4c3a-0000-4000-8000-000000000002","backupDestination":"nfs://192.168.0.31/export","backupAt":"2025-01-02T00:00:00+09:00","size":200}`
+)
+
+func TestBackupOp_CreateAndWait(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/nosql/backup", func(n int, _ *http.Request) (int, string) {
+		if n <= 2 {
+			return http.StatusOK, `{"nosql":{"backups":[` + backupOld + `]},"is_ok":true}`
+		}
+		return http.StatusOK, `{"nosql":{"backups":[` + backupOld + `,` + backupNew + `]},"is_ok":true}`
+	})
+	api.Handle("POST /appliance/123/nosql/backup", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"is_ok":true}`
+	})
+
+	backup, err := NewBackupOp(api.Client(), "123").CreateAndWait(t.Context(), fastWait)
+	assert.NoError(err)
+	assert.Equal("6e2b4c3a-0000-4000-8000-000000000002", backup.BackupId.String())
+	assert.Equal(int64(200), backup.Size)
+	assert.Equal("nfs://192.168.0.31/export", backup.BackupDestination)
+	assert.Equal(3, api.Calls("GET /appliance/123/nosql/backup"))
+}
+
+func TestBackupOp_RestoreAndWait(t *testing.T) {
+	assert := require.New(t)
+
+	const restoring = `{"backupId":"6e2b4c3a-0000-4000-8000-000000000001","backupDestination":"nfs://192.168.0.31/export","backupAt":"2025-01-01T00:00:00+09:00","size":100,"restoreStatus":"%s"}`
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/nosql/backup", func(n int, _ *http.Request) (int, string) {
+		status := RestoreStatusRestoring
+		switch {
+		case n == 1:
+			status = RestoreStatusNotRestored
+		case n >= 3:
+			status = RestoreStatusRestored
+		}
+		return http.StatusOK, `{"nosql":{"backups":[` + fmt.Sprintf(restoring, status) + `]}}`
+	})
+	api.Handle("PUT /appliance/123/nosql/backup/6e2b4c3a-0000-4000-8000-000000000001", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"is_ok":true}`
+	})
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		if n <= 2 {
+			return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"migrating"}}`
+		}
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"available"}}`
+	})
+
+	outcome, err := NewBackupOp(api.Client(), "123").RestoreAndWait(t.Context(),
+		uuid.MustParse("6e2b4c3a-0000-4000-8000-000000000001"), fastWait)
+	assert.NoError(err)
+	assert.Equal(RestoreSucceeded, outcome.Result)
+	assert.Equal(v1.AvailabilityAvailable, outcome.Availability)
+	assert.Equal(RestoreStatusRestored, outcome.Backup.RestoreStatus.Value)
+}
+
+func TestBackupOp_RestoreAndWait_Failed(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/nosql/backup", func(n int, _ *http.Request) (int, string) {
+		if n == 1 {
+			return http.StatusOK, `{"nosql":{"backups":[` + backupOld + `]}}`
+		}
+		return http.StatusOK, `{"nosql":{"backups":[` + strings.Replace(backupOld, `}`, `,"restoreStatus":"9"}`, 1) + `]}}`
+	})
+	api.Handle("PUT /appliance/123/nosql/backup/6e2b4c3a-0000-4000-8000-000000000001", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"is_ok":true}`
+	})
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"available"}}`
+	})
+
+	outcome, err := NewBackupOp(api.Client(), "123").RestoreAndWait(t.Context(),
+		uuid.MustParse("6e2b4c3a-0000-4000-8000-000000000001"), fastWait)
+	var failed *RestoreFailedError
+	assert.ErrorAs(err, &failed)
+	assert.Equal(RestoreFailed, outcome.Result)
+}
+
+func TestBackupOp_RestoreAndWait_PreviousFailure(t *testing.T) {
+	assert := require.New(t)
+
+	const backup = `{"backupId":"6e2b4c3a-0000-4000-8000-000000000001","backupDestination":"nfs://192.168.0.31/export","backupAt":"2025-01-01T00:00:00+09:00","size":100,"restoreStatus":"%s","restoreAt":"%s"}`
+	const previous = "2025-01-02T00:00:00+09:00"
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/nosql/backup", func(n int, _ *http.Request) (int, string) {
+		var b string
+		switch {
+		case n <= 2:
+			// 以前の復元の失敗が残っている
+			b = fmt.Sprintf(backup, RestoreStatusFailed, previous)
+		case n == 3:
+			b = fmt.Sprintf(backup, RestoreStatusRestoring, "2025-01-03T00:00:00+09:00")
+		default:
+			b = fmt.Sprintf(backup, RestoreStatusRestored, "2025-01-03T00:00:00+09:00")
+		}
+		return http.StatusOK, `{"nosql":{"backups":[` + b + `]}}`
+	})
+	api.Handle("PUT /appliance/123/nosql/backup/6e2b4c3a-0000-4000-8000-000000000001", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"is_ok":true}`
+	})
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"available"}}`
+	})
+
+	outcome, err := NewBackupOp(api.Client(), "123").RestoreAndWait(t.Context(),
+		uuid.MustParse("6e2b4c3a-0000-4000-8000-000000000001"), fastWait)
+	assert.NoError(err)
+	assert.Equal(RestoreSucceeded, outcome.Result)
+	assert.Equal(4, api.Calls("GET /appliance/123/nosql/backup"))
+}
@@ -0,0 +1,105 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"fmt"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// NodeLimitError ノード追加の上限を超えることを示すエラー
+type NodeLimitError struct {
+	Plan Plan
+	// NodeGroups 要求されたノードグループ数
+	NodeGroups    int
+	MaxNodeGroups int
+}
+
+func (e *NodeLimitError) Error() string {
+	if e.MaxNodeGroups == 0 {
+		return fmt.Sprintf("plan %s does not support adding nodes", e.Plan)
+	}
+	return fmt.Sprintf("plan %s supports up to %d node groups, requested %d", e.Plan, e.MaxNodeGroups, e.NodeGroups)
+}
+
+// ClusterCapacity プライマリと追加ノードグループからなるクラスタのノード数とリソースの合計
+type ClusterCapacity struct {
+	Plan          Plan
+	NodeGroups    int
+	MaxNodeGroups int
+	Nodes         int
+	MaxNodes      int
+	MemoryMB      int
+	DiskSizeMB    int
+	VirtualCore   int
+}
+
+// NewClusterCapacity プランと追加ノードグループ数からクラスタの容量を計算する
+func NewClusterCapacity(plan Plan, nodeGroups int) (*ClusterCapacity, error) {
+	spec, ok := plan.Spec()
+	if !ok {
+		return nil, NewError("NewClusterCapacity", fmt.Errorf("unknown plan %q", plan))
+	}
+	if nodeGroups < 0 || nodeGroups > spec.MaxNodeGroups {
+		return nil, NewError("NewClusterCapacity", &NodeLimitError{Plan: plan, NodeGroups: nodeGroups, MaxNodeGroups: spec.MaxNodeGroups})
+	}
+
+	nodes := spec.Nodes + nodeGroups*spec.NodesPerGroup
+	return &ClusterCapacity{
+		Plan:          plan,
+		NodeGroups:    nodeGroups,
+		MaxNodeGroups: spec.MaxNodeGroups,
+		Nodes:         nodes,
+		MaxNodes:      spec.Nodes + spec.MaxNodeGroups*spec.NodesPerGroup,
+		MemoryMB:      nodes * spec.MemoryMB,
+		DiskSizeMB:    nodes * spec.DiskSizeMB,
+		VirtualCore:   nodes * spec.VirtualCore,
+	}, nil
+}
+
+// ClusterCapacityFromAppliances プライマリと追加されたアプライアンスからクラスタの容量を計算する
+func ClusterCapacityFromAppliances(primary *v1.GetNosqlAppliance, added []v1.GetNosqlAppliance) (*ClusterCapacity, error) {
+	desc, err := DescribeAppliance(primary)
+	if err != nil {
+		return nil, err
+	}
+	if desc.Role != ApplianceRolePrimary {
+		return nil, NewError("ClusterCapacityFromAppliances", fmt.Errorf("appliance %s is not a primary appliance", desc.ID))
+	}
+	for i := range added {
+		d, err := DescribeAppliance(&added[i])
+		if err != nil {
+			return nil, err
+		}
+		if d.Role != ApplianceRoleAddNodes || d.ParentID != desc.ID || d.Plan != desc.Plan {
+			return nil, NewError("ClusterCapacityFromAppliances", fmt.Errorf("appliance %s is not a %s node group of %s", d.ID, desc.Plan, desc.ID))
+		}
+	}
+	return NewClusterCapacity(desc.Plan, len(added))
+}
+
+// CanAddNodeGroups さらにn回のノード追加が可能か
+func (c *ClusterCapacity) CanAddNodeGroups(n int) bool {
+	return c.NodeGroups+n <= c.MaxNodeGroups
+}
+
+// NodeGroupsForNodes プランで総ノード数をnodesにするために必要な追加ノードグループ数を返す
+func NodeGroupsForNodes(plan Plan, nodes int) (int, error) {
+	spec, ok := plan.Spec()
+	if !ok {
+		return 0, NewError("NodeGroupsForNodes", fmt.Errorf("unknown plan %q", plan))
+	}
+	if nodes == spec.Nodes {
+		return 0, nil
+	}
+	if spec.NodesPerGroup == 0 || nodes < spec.Nodes || (nodes-spec.Nodes)%spec.NodesPerGroup != 0 {
+		return 0, NewError("NodeGroupsForNodes", fmt.Errorf("plan %s cannot have %d nodes", plan, nodes))
+	}
+	groups := (nodes - spec.Nodes) / spec.NodesPerGroup
+	if groups > spec.MaxNodeGroups {
+		return 0, NewError("NodeGroupsForNodes", &NodeLimitError{Plan: plan, NodeGroups: groups, MaxNodeGroups: spec.MaxNodeGroups})
+	}
+	return groups, nil
+}
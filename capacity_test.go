@@ -0,0 +1,113 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterCapacityFromAppliances(t *testing.T) {
+	assert := require.New(t)
+
+	decode := func(s string) v1.GetNosqlAppliance {
+		var a v1.GetNosqlAppliance
+		assert.NoError(a.UnmarshalJSON([]byte(s)))
+		return a
+	}
+	primary := decode(`{"ID":"123","Plan":{"ID":51143},"Remark":{"Nosql":{"Zone":"tk1b"}}}`)
+	added := []v1.GetNosqlAppliance{
+		decode(`{"ID":"456","Plan":{"ID":51145},"Remark":{"Nosql":{"PrimaryNodes":{"Appliance":{"ID":"123"}}}}}`),
+	}
+
+	capacity, err := ClusterCapacityFromAppliances(&primary, added)
+	assert.NoError(err)
+	assert.Equal(&ClusterCapacity{
+		Plan:          Plan100GB,
+		NodeGroups:    1,
+		MaxNodeGroups: 3,
+		Nodes:         5,
+		MaxNodes:      9,
+		MemoryMB:      5 * 8192,
+		DiskSizeMB:    5 * 102400,
+		VirtualCore:   5 * 3,
+	}, capacity)
+	assert.True(capacity.CanAddNodeGroups(2))
+	assert.False(capacity.CanAddNodeGroups(3))
+
+	other := decode(`{"ID":"789","Plan":{"ID":51146},"Remark":{"Nosql":{"PrimaryNodes":{"Appliance":{"ID":"123"}}}}}`)
+	_, err = ClusterCapacityFromAppliances(&primary, []v1.GetNosqlAppliance{other})
+	assert.Error(err)
+}
+
+func TestNewClusterCapacity_Limit(t *testing.T) {
+	_, err := NewClusterCapacity(Plan40GB, 1)
+	var limit *NodeLimitError
+	require.ErrorAs(t, err, &limit)
+	require.Equal(t, 0, limit.MaxNodeGroups)
+}
+
+func TestInstanceOp_AddNodes_Limit(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/status", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"AddNodes":[` +
+			`{"Appliance":{"ID":"1","Availability":"available"}},` +
+			`{"Appliance":{"ID":"2","Availability":"available"}},` +
+			`{"Appliance":{"ID":"3","Availability":"available"}}]}}}}`
+	})
+
+	op := NewInstanceOp(api.Client(), "123", "tk1b")
+	_, err := op.AddNodes(t.Context(), Plan100GB, clusterApplianceRequest("group4"))
+	var limit *NodeLimitError
+	assert.ErrorAs(err, &limit)
+	assert.Equal(4, limit.NodeGroups)
+	assert.Equal(0, api.Calls("POST /appliance"))
+
+	_, err = op.AddNodes(t.Context(), Plan40GB, clusterApplianceRequest("group1"))
+	assert.ErrorAs(err, &limit)
+	assert.Equal(Plan40GB, limit.Plan)
+}
+
+func TestNodeGroupsForNodes(t *testing.T) {
+	cases := []struct {
+		plan    Plan
+		nodes   int
+		groups  int
+		wantErr bool
+	}{
+		{Plan40GB, 1, 0, false},
+		{Plan40GB, 3, 0, true},
+		{Plan100GB, 3, 0, false},
+		{Plan100GB, 5, 1, false},
+		{Plan250GB, 9, 3, false},
+		{Plan250GB, 11, 0, true},
+		{Plan250GB, 4, 0, true},
+		{Plan100GB, 1, 0, true},
+	}
+	for _, tc := range cases {
+		groups, err := NodeGroupsForNodes(tc.plan, tc.nodes)
+		if tc.wantErr {
+			require.Error(t, err, "%s/%d", tc.plan, tc.nodes)
+			continue
+		}
+		require.NoError(t, err, "%s/%d", tc.plan, tc.nodes)
+		require.Equal(t, tc.groups, groups, "%s/%d", tc.plan, tc.nodes)
+	}
+}
+
+func TestNodeGroupsForNodes_Error(t *testing.T) {
+	assert := require.New(t)
+
+	_, err := NodeGroupsForNodes(Plan250GB, 11)
+	var limit *NodeLimitError
+	assert.ErrorAs(err, &limit)
+	assert.Equal(4, limit.NodeGroups)
+	assert.Equal("nosql: NodeGroupsForNodes: plan 250GB supports up to 3 node groups, requested 4", err.Error())
+}
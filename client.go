@@ -9,6 +9,7 @@ import (
 
 	v1 "github.com/sacloud/nosql-api-go/apis/v1"
 	"github.com/sacloud/saclient-go"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -27,7 +28,30 @@ var UserAgent = fmt.Sprintf(
 	runtime.GOARCH,
 )
 
-func NewClient(client saclient.ClientAPI) (*v1.Client, error) {
+// ClientOption NewClientで作成するクライアントの設定
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	middlewares     []saclient.Middleware
+	httpMiddlewares []Middleware
+	tracerProvider  trace.TracerProvider
+	metrics         Metrics
+}
+
+// Client v1.Clientに、オペレーションが参照する接続先のゾーンやTracer、Metricsを加えたもの。v1.Clientのメソッドもそのまま使える
+type Client struct {
+	*v1.Client
+	zone    string
+	tracer  trace.Tracer
+	metrics Metrics
+}
+
+// Zone クライアントが接続するゾーンを返す。APIルートURLからゾーンを判定できない場合は空
+func (c *Client) Zone() string {
+	return c.zone
+}
+
+func NewClient(client saclient.ClientAPI, opts ...ClientOption) (*Client, error) {
 	endpointConfig, err := client.EndpointConfig()
 	if err != nil {
 		return nil, NewError("unable to load endpoint configuration", err)
@@ -36,21 +60,35 @@ func NewClient(client saclient.ClientAPI) (*v1.Client, error) {
 	if ep, ok := endpointConfig.Endpoints[ServiceKey]; ok && ep != "" {
 		endpoint = ep
 	}
-	return NewClientWithAPIRootURL(client, endpoint)
+	return NewClientWithAPIRootURL(client, endpoint, opts...)
 }
 
-func NewClientWithAPIRootURL(client saclient.ClientAPI, apiRootURL string) (*v1.Client, error) {
+func NewClientWithAPIRootURL(client saclient.ClientAPI, apiRootURL string, opts ...ClientOption) (*Client, error) {
+	return newClient(client, apiRootURL, zoneFromURL(apiRootURL), opts)
+}
+
+func newClient(client saclient.ClientAPI, apiRootURL string, zone string, opts []ClientOption) (*Client, error) {
 	dupable, ok := client.(saclient.ClientOptionAPI)
 	if !ok {
 		return nil, NewError("client does not implement saclient.ClientOptionAPI", nil)
 	}
 
+	var config clientConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	augmented, err := dupable.DupWith(
 		saclient.WithUserAgent(UserAgent),
 		saclient.WithBigInt(false), // 文字列を勝手に数値に変換しないようヘッダーで指定
+		saclient.WithMiddleware(config.middlewares...),
 	)
 	if err != nil {
 		return nil, err
 	}
-	return v1.NewClient(apiRootURL, v1.WithClient(augmented))
+	c, err := v1.NewClient(apiRootURL, v1.WithClient(chainMiddlewares(augmented, config.httpMiddlewares)))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: c, zone: zone, tracer: newTracer(config.tracerProvider), metrics: config.metrics}, nil
 }
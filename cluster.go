@@ -0,0 +1,218 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// DeleteClusterOptions DeleteClusterの設定
+type DeleteClusterOptions struct {
+	// StopFirst trueの場合、削除前に起動中のアプライアンスを停止する
+	StopFirst bool
+	// Wait 停止と削除完了の待機に使う設定
+	Wait *WaitOptions
+}
+
+// ApplianceDeleteResult DeleteClusterでのアプライアンス毎の結果
+type ApplianceDeleteResult struct {
+	ApplianceID string
+	// Primary プライマリのアプライアンスかどうか
+	Primary bool
+	Stopped bool
+	Deleted bool
+	// NotAttempted 先に削除するアプライアンスで失敗したため削除を試みなかったかどうか。このアプライアンスは残っている
+	NotAttempted bool
+	Err          error
+}
+
+// DeleteCluster プライマリのアプライアンスとAddNodesで追加されたアプライアンスを削除する。
+// 追加されたアプライアンスを後から追加したものから順に削除し、全て削除できた場合のみプライマリを削除する。
+// 各アプライアンスは削除完了(Readが404を返す)まで待機する。
+// 結果は全てのアプライアンスについて返し、失敗により削除を試みなかったものはNotAttemptedになる
+func (op *databaseOp) DeleteCluster(ctx context.Context, primaryID string, opts *DeleteClusterOptions) (_ []ApplianceDeleteResult, err error) {
+	ctx, span := startSpan(ctx, op.client, "Database.DeleteCluster", primaryID)
+	defer func() { endSpan(span, err) }()
+
+	if opts == nil {
+		opts = &DeleteClusterOptions{}
+	}
+
+	status, err := op.GetStatus(ctx, primaryID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(status.AddNodes)+1)
+	for i := len(status.AddNodes) - 1; i >= 0; i-- {
+		ids = append(ids, status.AddNodes[i].Appliance.ID)
+	}
+	ids = append(ids, primaryID)
+	return op.deleteAppliances(ctx, primaryID, ids, opts)
+}
+
+// deleteAppliances idsの順にアプライアンスを削除する。
+// 失敗した場合は以降のアプライアンスを削除せず、NotAttemptedの結果を返す
+func (op *databaseOp) deleteAppliances(ctx context.Context, primaryID string, ids []string, opts *DeleteClusterOptions) ([]ApplianceDeleteResult, error) {
+	results := make([]ApplianceDeleteResult, 0, len(ids))
+	var err error
+	for _, id := range ids {
+		result := ApplianceDeleteResult{ApplianceID: id, Primary: id == primaryID}
+		if err != nil {
+			result.NotAttempted = true
+			results = append(results, result)
+			continue
+		}
+		result.Stopped, result.Err = op.deleteAndWait(ctx, id, opts)
+		result.Deleted = result.Err == nil
+		results = append(results, result)
+		err = result.Err
+	}
+	return results, err
+}
+
+func (op *databaseOp) deleteAndWait(ctx context.Context, id string, opts *DeleteClusterOptions) (stopped bool, err error) {
+	if opts.StopFirst {
+		appliance, err := op.Read(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		if appliance.Instance.Value.Status.Value != InstanceStatusDown {
+			if _, err := NewInstanceOp(op.client, id, op.client.Zone(), op.options()...).StopAndWait(ctx, opts.Wait); err != nil {
+				return false, err
+			}
+			stopped = true
+		}
+	}
+
+	if err := op.Delete(ctx, id); err != nil {
+		return stopped, err
+	}
+	return stopped, op.waitUntilDeleted(ctx, id, opts.Wait)
+}
+
+func (op *databaseOp) waitUntilDeleted(ctx context.Context, id string, opts *WaitOptions) error {
+	return poll(ctx, metricsOf(op.client), "Database.WaitUntilDeleted", opts, func(ctx context.Context) (string, bool, error) {
+		appliance, err := op.Read(ctx, id)
+		if err != nil {
+			if IsNotFound(err) {
+				return "deleted", true, nil
+			}
+			return "", false, err
+		}
+		return string(appliance.Availability.Value), false, nil
+	})
+}
+
+// CreateClusterRequest CreateClusterのリクエスト
+type CreateClusterRequest struct {
+	Plan Plan
+	// Nodes クラスタ全体のノード数
+	Nodes int
+	// Primary プライマリのアプライアンスの作成リクエスト
+	Primary v1.NosqlCreateRequestAppliance
+	// NodeGroups 追加するノードグループ毎のAddNodesのリクエスト。Nodesから求めたグループ数と同じ数が必要
+	NodeGroups []v1.NosqlCreateRequestAppliance
+}
+
+// DefaultRollbackTimeout CreateClusterのロールバックのデフォルトのタイムアウト
+const DefaultRollbackTimeout = 60 * time.Minute
+
+// CreateClusterOptions CreateClusterの設定
+type CreateClusterOptions struct {
+	// RollbackOnFailure trueの場合、途中で失敗したら作成済みのノードグループを後から追加したものから順に削除し、最後にプライマリを削除する。
+	// ctxがキャンセルされた場合もロールバックは行われる
+	RollbackOnFailure bool
+	// RollbackTimeout ロールバック全体のタイムアウト。0の場合はDefaultRollbackTimeout
+	RollbackTimeout time.Duration
+	// Wait 各アプライアンスの作成完了の待機に使う設定
+	Wait *WaitOptions
+}
+
+// CreateClusterResult CreateClusterの結果。失敗した場合もそこまでの進捗を保持する
+type CreateClusterResult struct {
+	PrimaryID    string
+	NodeGroupIDs []string
+	// RolledBack ロールバックを行ったかどうか
+	RolledBack bool
+	// Rollback ロールバック時のアプライアンス毎の削除結果
+	Rollback []ApplianceDeleteResult
+}
+
+// CreateCluster プライマリのアプライアンスを作成してavailableになるまで待機し、
+// 続けてノードグループを1つずつ追加してそれぞれavailableになるまで待機する
+func (op *databaseOp) CreateCluster(ctx context.Context, request CreateClusterRequest, opts *CreateClusterOptions) (_ *CreateClusterResult, err error) {
+	ctx, span := startSpan(ctx, op.client, "Database.CreateCluster", "")
+	defer func() { endSpan(span, err) }()
+
+	if opts == nil {
+		opts = &CreateClusterOptions{}
+	}
+	groups, err := NodeGroupsForNodes(request.Plan, request.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	if len(request.NodeGroups) != groups {
+		return nil, NewError("Database.CreateCluster", fmt.Errorf("%d nodes require %d node group requests, got %d", request.Nodes, groups, len(request.NodeGroups)))
+	}
+
+	result := &CreateClusterResult{}
+	err = op.createCluster(ctx, request, opts, result)
+	if result.PrimaryID != "" {
+		span.SetAttributes(AttributeApplianceID.String(result.PrimaryID))
+	}
+	if err != nil {
+		if opts.RollbackOnFailure && result.PrimaryID != "" {
+			result.RolledBack = true
+			// AddNodesで作成したノードグループはGetStatusにまだ現れないことがあるため、DeleteClusterを使わず作成したIDを明示して削除する
+			ids := make([]string, 0, len(result.NodeGroupIDs)+1)
+			for i := len(result.NodeGroupIDs) - 1; i >= 0; i-- {
+				ids = append(ids, result.NodeGroupIDs[i])
+			}
+			ids = append(ids, result.PrimaryID)
+			// キャンセルやタイムアウトで失敗した場合もアプライアンスを残さないよう、ctxのキャンセルを引き継がない
+			timeout := opts.RollbackTimeout
+			if timeout <= 0 {
+				timeout = DefaultRollbackTimeout
+			}
+			rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+			defer cancel()
+			var rollbackErr error
+			result.Rollback, rollbackErr = op.deleteAppliances(rollbackCtx, result.PrimaryID, ids, &DeleteClusterOptions{StopFirst: true, Wait: opts.Wait})
+			if rollbackErr != nil {
+				return result, errors.Join(err, NewError("Database.CreateCluster: rollback", rollbackErr))
+			}
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+func (op *databaseOp) createCluster(ctx context.Context, request CreateClusterRequest, opts *CreateClusterOptions, result *CreateClusterResult) error {
+	primary, err := op.Create(ctx, request.Plan, request.Primary)
+	if err != nil {
+		return err
+	}
+	result.PrimaryID = primary.ID.Value
+	if _, err := op.WaitUntilAvailable(ctx, result.PrimaryID, opts.Wait); err != nil {
+		return err
+	}
+
+	instanceOp := NewInstanceOp(op.client, result.PrimaryID, request.Primary.Remark.Nosql.Zone, op.options()...)
+	for _, group := range request.NodeGroups {
+		added, err := instanceOp.AddNodes(ctx, request.Plan, group)
+		if err != nil {
+			return err
+		}
+		result.NodeGroupIDs = append(result.NodeGroupIDs, added.ID.Value)
+		if _, err := op.WaitUntilAvailable(ctx, added.ID.Value, opts.Wait); err != nil {
+			return err
+		}
+	}
+	return nil
+}
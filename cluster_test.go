@@ -0,0 +1,334 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseOp_DeleteCluster(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/status", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"AddNodes":[` +
+			`{"Appliance":{"ID":"456","Availability":"available"}},` +
+			`{"Appliance":{"ID":"789","Availability":"available"}}]}}}}`
+	})
+
+	var (
+		mu      sync.Mutex
+		deleted []string
+	)
+	for _, id := range []string{"123", "456", "789"} {
+		api.Handle("DELETE /appliance/"+id, func(int, *http.Request) (int, string) {
+			mu.Lock()
+			defer mu.Unlock()
+			deleted = append(deleted, id)
+			return http.StatusOK, `{"Success":true,"is_ok":true}`
+		})
+		api.Handle("GET /appliance/"+id, func(int, *http.Request) (int, string) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, d := range deleted {
+				if d == id {
+					return http.StatusNotFound, `{"is_fatal":true,"serial":"xxx","status":"404 Not Found","error_code":"not_found","error_msg":"not found"}`
+				}
+			}
+			return http.StatusOK, `{"Appliance":{"ID":"` + id + `","Availability":"available","Instance":{"Status":"down"}}}`
+		})
+	}
+
+	results, err := NewDatabaseOp(api.Client()).DeleteCluster(t.Context(), "123",
+		&DeleteClusterOptions{StopFirst: true, Wait: fastWait})
+	assert.NoError(err)
+	assert.Equal([]string{"789", "456", "123"}, deleted)
+	assert.Equal([]ApplianceDeleteResult{
+		{ApplianceID: "789", Deleted: true},
+		{ApplianceID: "456", Deleted: true},
+		{ApplianceID: "123", Primary: true, Deleted: true},
+	}, results)
+}
+
+func TestDatabaseOp_DeleteCluster_StopFirst(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/status", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{}}}}`
+	})
+
+	var (
+		mu        sync.Mutex
+		events    []string
+		stoppedAt int
+		deleted   bool
+	)
+	api.Handle("DELETE /appliance/123/power", func(int, *http.Request) (int, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, "stop")
+		stoppedAt = api.Calls("GET /appliance/123")
+		return http.StatusAccepted, `{"Success":true,"is_ok":true}`
+	})
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case deleted:
+			return http.StatusNotFound, `{"is_fatal":true,"serial":"xxx","status":"404 Not Found","error_code":"not_found","error_msg":"not found"}`
+		case stoppedAt == 0 || n <= stoppedAt+1:
+			// 停止の要求直後はまだ起動中
+			return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"available","Instance":{"Status":"up"}}}`
+		default:
+			events = append(events, "down")
+			return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"available","Instance":{"Status":"down"}}}`
+		}
+	})
+	api.Handle("DELETE /appliance/123", func(int, *http.Request) (int, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, "delete")
+		deleted = true
+		return http.StatusOK, `{"Success":true,"is_ok":true}`
+	})
+
+	results, err := NewDatabaseOp(api.Client()).DeleteCluster(t.Context(), "123",
+		&DeleteClusterOptions{StopFirst: true, Wait: fastWait})
+	assert.NoError(err)
+	assert.Equal([]string{"stop", "down", "delete"}, events)
+	assert.Equal([]ApplianceDeleteResult{{ApplianceID: "123", Primary: true, Stopped: true, Deleted: true}}, results)
+	assert.Equal(1, api.Calls("DELETE /appliance/123/power"))
+}
+
+func TestDatabaseOp_DeleteCluster_StopsOnFailure(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/status", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"AddNodes":[{"Appliance":{"ID":"456","Availability":"available"}}]}}}}`
+	})
+	api.Handle("DELETE /appliance/456", func(int, *http.Request) (int, string) {
+		return http.StatusConflict, `{"is_fatal":true,"serial":"xxx","status":"409 Conflict","error_code":"busy","error_msg":"busy"}`
+	})
+
+	results, err := NewDatabaseOp(api.Client()).DeleteCluster(t.Context(), "123", nil)
+	assert.Error(err)
+	assert.Len(results, 2)
+	assert.Equal("456", results[0].ApplianceID)
+	assert.False(results[0].Deleted)
+	assert.Error(results[0].Err)
+	assert.Equal(ApplianceDeleteResult{ApplianceID: "123", Primary: true, NotAttempted: true}, results[1])
+	assert.Equal(0, api.Calls("DELETE /appliance/123"))
+}
+
+func TestDatabaseOp_CreateCluster(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	api.Handle("POST /appliance", func(n int, r *http.Request) (int, string) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		id := "123"
+		if n > 1 {
+			id = "456"
+		}
+		return http.StatusAccepted, `{"Appliance":{"ID":"` + id + `","Availability":"migrating"},"Success":true,"is_ok":true}`
+	})
+	for _, id := range []string{"123", "456"} {
+		api.Handle("GET /appliance/"+id, func(int, *http.Request) (int, string) {
+			return http.StatusOK, `{"Appliance":{"ID":"` + id + `","Availability":"available"}}`
+		})
+	}
+	api.Handle("GET /appliance/123/status", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{}}}}`
+	})
+
+	result, err := NewDatabaseOp(api.Client()).CreateCluster(t.Context(), CreateClusterRequest{
+		Plan:       Plan100GB,
+		Nodes:      5,
+		Primary:    clusterApplianceRequest("primary"),
+		NodeGroups: []v1.NosqlCreateRequestAppliance{clusterApplianceRequest("group1")},
+	}, &CreateClusterOptions{Wait: fastWait})
+	assert.NoError(err)
+	assert.Equal("123", result.PrimaryID)
+	assert.Equal([]string{"456"}, result.NodeGroupIDs)
+	assert.Len(bodies, 2)
+	assert.Contains(bodies[1], `"PrimaryNodes":{"Appliance":{"ID":"123","Zone":{"Name":"tk1b"}}}`)
+}
+
+func TestDatabaseOp_CreateCluster_Rollback(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("POST /appliance", func(int, *http.Request) (int, string) {
+		return http.StatusAccepted, `{"Appliance":{"ID":"123","Availability":"migrating"},"Success":true,"is_ok":true}`
+	})
+	var deleted atomic.Bool
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		if deleted.Load() {
+			return http.StatusNotFound, `{"is_fatal":true,"error_msg":"not found"}`
+		}
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"failed","Instance":{"Status":"down"}}}`
+	})
+	api.Handle("GET /appliance/123/status", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{}}}}`
+	})
+	api.Handle("DELETE /appliance/123", func(int, *http.Request) (int, string) {
+		deleted.Store(true)
+		return http.StatusOK, `{"Success":true,"is_ok":true}`
+	})
+
+	result, err := NewDatabaseOp(api.Client()).CreateCluster(t.Context(), CreateClusterRequest{
+		Plan:    Plan40GB,
+		Nodes:   1,
+		Primary: clusterApplianceRequest("primary"),
+	}, &CreateClusterOptions{RollbackOnFailure: true, Wait: fastWait})
+	var failed *ApplianceFailedError
+	assert.ErrorAs(err, &failed)
+	assert.True(result.RolledBack)
+	assert.Equal([]ApplianceDeleteResult{{ApplianceID: "123", Primary: true, Deleted: true}}, result.Rollback)
+}
+
+func TestDatabaseOp_CreateCluster_RollbackNodeGroups(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("POST /appliance", func(n int, _ *http.Request) (int, string) {
+		id := "123"
+		if n > 1 {
+			id = "456"
+		}
+		return http.StatusAccepted, `{"Appliance":{"ID":"` + id + `","Availability":"migrating"},"Success":true,"is_ok":true}`
+	})
+	// 追加したノードグループはGetStatusのAddNodesにまだ現れていない
+	api.Handle("GET /appliance/123/status", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{}}}}`
+	})
+
+	var (
+		mu      sync.Mutex
+		deleted []string
+	)
+	for _, id := range []string{"123", "456"} {
+		availability := "available"
+		if id == "456" {
+			availability = "failed"
+		}
+		api.Handle("GET /appliance/"+id, func(int, *http.Request) (int, string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if slices.Contains(deleted, id) {
+				return http.StatusNotFound, `{"is_fatal":true,"error_msg":"not found"}`
+			}
+			return http.StatusOK, `{"Appliance":{"ID":"` + id + `","Availability":"` + availability + `","Instance":{"Status":"down"}}}`
+		})
+		api.Handle("DELETE /appliance/"+id, func(int, *http.Request) (int, string) {
+			mu.Lock()
+			defer mu.Unlock()
+			deleted = append(deleted, id)
+			return http.StatusOK, `{"Success":true,"is_ok":true}`
+		})
+	}
+
+	result, err := NewDatabaseOp(api.Client()).CreateCluster(t.Context(), CreateClusterRequest{
+		Plan:       Plan100GB,
+		Nodes:      5,
+		Primary:    clusterApplianceRequest("primary"),
+		NodeGroups: []v1.NosqlCreateRequestAppliance{clusterApplianceRequest("group1")},
+	}, &CreateClusterOptions{RollbackOnFailure: true, Wait: fastWait})
+	var failed *ApplianceFailedError
+	assert.ErrorAs(err, &failed)
+	assert.Equal("456", failed.ID)
+	assert.True(result.RolledBack)
+	assert.Equal([]string{"456", "123"}, deleted)
+	assert.Equal([]ApplianceDeleteResult{
+		{ApplianceID: "456", Deleted: true},
+		{ApplianceID: "123", Primary: true, Deleted: true},
+	}, result.Rollback)
+}
+
+func TestDatabaseOp_CreateCluster_RollbackAfterCancel(t *testing.T) {
+	assert := require.New(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	api := newFakeAPI(t)
+	api.Handle("POST /appliance", func(int, *http.Request) (int, string) {
+		return http.StatusAccepted, `{"Appliance":{"ID":"123","Availability":"migrating"},"Success":true,"is_ok":true}`
+	})
+	var (
+		mu      sync.Mutex
+		deleted bool
+	)
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case n == 1:
+			// プライマリの作成完了を待っている間に呼び出し元がキャンセルする
+			cancel()
+			return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"migrating","Instance":{"Status":"down"}}}`
+		case deleted:
+			return http.StatusNotFound, `{"is_fatal":true,"error_msg":"not found"}`
+		}
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"available","Instance":{"Status":"down"}}}`
+	})
+	api.Handle("DELETE /appliance/123", func(int, *http.Request) (int, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		deleted = true
+		return http.StatusOK, `{"Success":true,"is_ok":true}`
+	})
+
+	result, err := NewDatabaseOp(api.Client()).CreateCluster(ctx, CreateClusterRequest{
+		Plan:    Plan100GB,
+		Nodes:   3,
+		Primary: clusterApplianceRequest("primary"),
+	}, &CreateClusterOptions{RollbackOnFailure: true, Wait: &WaitOptions{Interval: 50 * time.Millisecond, Timeout: 5 * time.Second}})
+	assert.ErrorIs(err, context.Canceled)
+	assert.True(result.RolledBack)
+	assert.Equal([]ApplianceDeleteResult{{ApplianceID: "123", Primary: true, Deleted: true}}, result.Rollback)
+	assert.Equal(1, api.Calls("DELETE /appliance/123"))
+}
+
+func clusterApplianceRequest(name string) v1.NosqlCreateRequestAppliance {
+	return v1.NosqlCreateRequestAppliance{
+		Name: name,
+		Remark: v1.NosqlRemark{
+			Nosql:   v1.NosqlRemarkNosql{Zone: "tk1b"},
+			Servers: []v1.NosqlRemarkServersItem{{UserIPAddress: netip.MustParseAddr("192.168.0.4")}},
+			Network: v1.NosqlRemarkNetwork{DefaultRoute: "192.168.0.1", NetworkMaskLen: 24},
+		},
+		UserInterfaces: []v1.NosqlCreateRequestApplianceUserInterfacesItem{
+			{
+				Switch:         v1.NosqlCreateRequestApplianceUserInterfacesItemSwitch{ID: "111111111111"},
+				UserIPAddress1: netip.MustParseAddr("192.168.0.4"),
+				UserSubnet: v1.NosqlCreateRequestApplianceUserInterfacesItemUserSubnet{
+					DefaultRoute:   "192.168.0.1",
+					NetworkMaskLen: 24,
+				},
+			},
+		},
+	}
+}
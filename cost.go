@@ -0,0 +1,139 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"fmt"
+	"strconv"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// PlanPrice プランID毎の料金。通貨や税の扱いは料金表を用意する側で決める
+type PlanPrice struct {
+	Monthly float64 `json:"monthly" yaml:"monthly"`
+	Hourly  float64 `json:"hourly" yaml:"hourly"`
+}
+
+// PriceTable プランID(ノード追加用のプランIDを含む)をキーとする料金表
+type PriceTable map[int]PlanPrice
+
+// LoadPriceTable JSONまたはYAMLのドキュメントから料金表を読み込む。
+// JSONのキーは文字列になるため、キーは文字列・数値のどちらで記述してもよい
+//
+//	51143: {monthly: 100000, hourly: 150}
+//	"51145": {monthly: 60000, hourly: 90}
+func LoadPriceTable(data []byte) (PriceTable, error) {
+	var raw map[string]PlanPrice
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, NewError("unable to parse price table", err)
+	}
+	table := make(PriceTable, len(raw))
+	for key, price := range raw {
+		planID, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, NewError("unable to parse price table", fmt.Errorf("plan ID %q is not an integer", key))
+		}
+		table[planID] = price
+	}
+	return table, nil
+}
+
+// CostItem 見積もりの明細
+type CostItem struct {
+	// ApplianceID 既存のアプライアンスから見積もった場合のアプライアンスID
+	ApplianceID string
+	Plan        Plan
+	PlanID      int
+	// ForNodes ノード追加用のプランかどうか
+	ForNodes bool
+	Monthly  float64
+	Hourly   float64
+}
+
+// CostEstimate 見積もりの結果
+type CostEstimate struct {
+	Items   []CostItem
+	Monthly float64
+	Hourly  float64
+}
+
+func (e *CostEstimate) add(item CostItem) {
+	e.Items = append(e.Items, item)
+	e.Monthly += item.Monthly
+	e.Hourly += item.Hourly
+}
+
+func (t PriceTable) item(plan Plan, forNodes bool) (CostItem, error) {
+	planID := plan.GetPlanID()
+	if forNodes {
+		planID = plan.GetPlanIDforNodes()
+	}
+	price, ok := t[planID]
+	if planID == 0 || !ok {
+		return CostItem{}, NewError(fmt.Sprintf("no price for plan %s (plan ID %d)", describePlan(plan, forNodes), planID), nil)
+	}
+	return CostItem{Plan: plan, PlanID: planID, ForNodes: forNodes, Monthly: price.Monthly, Hourly: price.Hourly}, nil
+}
+
+// EstimatePlan プライマリとnodeGroups個の追加ノードグループからなるクラスタの料金を見積もる
+func (t PriceTable) EstimatePlan(plan Plan, nodeGroups int) (*CostEstimate, error) {
+	if _, err := NewClusterCapacity(plan, nodeGroups); err != nil {
+		return nil, err
+	}
+	estimate := &CostEstimate{}
+	primary, err := t.item(plan, false)
+	if err != nil {
+		return nil, err
+	}
+	estimate.add(primary)
+	if nodeGroups > 0 {
+		group, err := t.item(plan, true)
+		if err != nil {
+			return nil, err
+		}
+		for range nodeGroups {
+			estimate.add(group)
+		}
+	}
+	return estimate, nil
+}
+
+// EstimateAddNodes 現在のクラスタにnodeGroups個のノードグループを追加した場合に増える料金を見積もる
+func (t PriceTable) EstimateAddNodes(current *ClusterCapacity, nodeGroups int) (*CostEstimate, error) {
+	if !current.CanAddNodeGroups(nodeGroups) {
+		return nil, NewError("PriceTable.EstimateAddNodes", &NodeLimitError{Plan: current.Plan, NodeGroups: current.NodeGroups + nodeGroups, MaxNodeGroups: current.MaxNodeGroups})
+	}
+	estimate := &CostEstimate{}
+	if nodeGroups <= 0 {
+		return estimate, nil
+	}
+	group, err := t.item(current.Plan, true)
+	if err != nil {
+		return nil, err
+	}
+	for range nodeGroups {
+		estimate.add(group)
+	}
+	return estimate, nil
+}
+
+// EstimateAppliances ReadやListで取得したアプライアンスの料金を見積もる
+func (t PriceTable) EstimateAppliances(appliances []v1.GetNosqlAppliance) (*CostEstimate, error) {
+	estimate := &CostEstimate{}
+	for i := range appliances {
+		desc, err := DescribeAppliance(&appliances[i])
+		if err != nil {
+			return nil, err
+		}
+		item, err := t.item(desc.Plan, desc.Role == ApplianceRoleAddNodes)
+		if err != nil {
+			return nil, err
+		}
+		item.ApplianceID = desc.ID
+		estimate.add(item)
+	}
+	return estimate, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"testing"
+
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+const priceTableYAML = `
+51142: {monthly: 30000, hourly: 45}
+51143: {monthly: 100000, hourly: 150}
+51145: {monthly: 60000, hourly: 90}
+`
+
+const priceTableJSON = `{
+  "51142": {"monthly": 30000, "hourly": 45},
+  "51143": {"monthly": 100000, "hourly": 150},
+  "51145": {"monthly": 60000, "hourly": 90}
+}`
+
+func TestLoadPriceTable(t *testing.T) {
+	assert := require.New(t)
+
+	fromYAML, err := LoadPriceTable([]byte(priceTableYAML))
+	assert.NoError(err)
+	fromJSON, err := LoadPriceTable([]byte(priceTableJSON))
+	assert.NoError(err)
+	assert.Equal(fromYAML, fromJSON)
+	assert.Equal(PlanPrice{Monthly: 100000, Hourly: 150}, fromJSON[51143])
+
+	_, err = LoadPriceTable([]byte(`{"100GB": {"monthly": 100000}}`))
+	assert.ErrorContains(err, `plan ID "100GB" is not an integer`)
+}
+
+func TestPriceTable_EstimatePlan(t *testing.T) {
+	assert := require.New(t)
+
+	table, err := LoadPriceTable([]byte(priceTableYAML))
+	assert.NoError(err)
+
+	estimate, err := table.EstimatePlan(Plan100GB, 2)
+	assert.NoError(err)
+	assert.Len(estimate.Items, 3)
+	assert.Equal(51143, estimate.Items[0].PlanID)
+	assert.True(estimate.Items[1].ForNodes)
+	assert.InDelta(220000, estimate.Monthly, 0.001)
+	assert.InDelta(330, estimate.Hourly, 0.001)
+
+	_, err = table.EstimatePlan(Plan100GB, 4)
+	var limit *NodeLimitError
+	assert.ErrorAs(err, &limit)
+
+	_, err = table.EstimatePlan(Plan250GB, 0)
+	assert.Error(err)
+}
+
+func TestPriceTable_EstimateAddNodes(t *testing.T) {
+	assert := require.New(t)
+
+	table, err := LoadPriceTable([]byte(priceTableYAML))
+	assert.NoError(err)
+
+	current, err := NewClusterCapacity(Plan100GB, 2)
+	assert.NoError(err)
+
+	estimate, err := table.EstimateAddNodes(current, 1)
+	assert.NoError(err)
+	assert.InDelta(60000, estimate.Monthly, 0.001)
+
+	_, err = table.EstimateAddNodes(current, 2)
+	var limit *NodeLimitError
+	assert.ErrorAs(err, &limit)
+}
+
+func TestPriceTable_EstimateAppliances(t *testing.T) {
+	assert := require.New(t)
+
+	table, err := LoadPriceTable([]byte(priceTableYAML))
+	assert.NoError(err)
+
+	var primary, added v1.GetNosqlAppliance
+	assert.NoError(primary.UnmarshalJSON([]byte(`{"ID":"123","Plan":{"ID":51143}}`)))
+	assert.NoError(added.UnmarshalJSON([]byte(`{"ID":"456","Plan":{"ID":51145},"Remark":{"Nosql":{"PrimaryNodes":{"Appliance":{"ID":"123"}}}}}`)))
+
+	estimate, err := table.EstimateAppliances([]v1.GetNosqlAppliance{primary, added})
+	assert.NoError(err)
+	assert.Equal([]CostItem{
+		{ApplianceID: "123", Plan: Plan100GB, PlanID: 51143, Monthly: 100000, Hourly: 150},
+		{ApplianceID: "456", Plan: Plan100GB, PlanID: 51145, ForNodes: true, Monthly: 60000, Hourly: 90},
+	}, estimate.Items)
+	assert.InDelta(160000, estimate.Monthly, 0.001)
+}
@@ -5,7 +5,7 @@ package nosql
 
 import (
 	"context"
-	"errors"
+	"fmt"
 
 	v1 "github.com/sacloud/nosql-api-go/apis/v1"
 )
@@ -18,39 +18,59 @@ type DatabaseAPI interface {
 	Delete(ctx context.Context, id string) error
 	ApplyChanges(ctx context.Context, id string) error
 	GetStatus(ctx context.Context, id string) (*v1.NosqlStatusResponseApplianceSettingsResponseNosql, error)
+	WaitUntilAvailable(ctx context.Context, id string, opts *WaitOptions) (*v1.GetNosqlAppliance, error)
+	UpdateAndApply(ctx context.Context, id string, mutate func(*v1.NosqlSettings) error) error
+	CreateCluster(ctx context.Context, request CreateClusterRequest, opts *CreateClusterOptions) (*CreateClusterResult, error)
+	DeleteCluster(ctx context.Context, primaryID string, opts *DeleteClusterOptions) ([]ApplianceDeleteResult, error)
 }
 
 var _ DatabaseAPI = (*databaseOp)(nil)
 
 type databaseOp struct {
-	client *v1.Client
+	client *Client
+	opConfig
 }
 
-func NewDatabaseOp(client *v1.Client) DatabaseAPI {
-	return &databaseOp{client: client}
+func NewDatabaseOp(client *Client, opts ...OpOption) DatabaseAPI {
+	return &databaseOp{client: client, opConfig: newOpConfig(opts)}
 }
 
 func (op *databaseOp) List(ctx context.Context) ([]v1.GetNosqlAppliance, error) {
-	res, err := op.client.ListDB(ctx, v1.ListDBParams{FilterClass: "nosql"})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.ListDBOperation, func(ctx context.Context) (v1.ListDBRes, error) {
+		return op.client.ListDB(ctx, v1.ListDBParams{FilterClass: "nosql"})
+	})
 	if err != nil {
-		return nil, NewAPIError("Database.List", 0, err)
+		return nil, newAPIError(ctx, "Database.List", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlListResponse:
 		return p.Appliances, nil
 	case *v1.BadRequestResponse:
-		return nil, NewAPIError("Database.List", 400, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.List", 400, p)
 	case *v1.UnauthorizedResponse:
-		return nil, NewAPIError("Database.List", 401, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.List", 401, p)
 	case *v1.ServerErrorResponse:
-		return nil, NewAPIError("Database.List", 500, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.List", 500, p)
 	default:
-		return nil, NewAPIError("Database.List", 0, nil)
+		return nil, newAPIError(ctx, "Database.List", 0, nil)
 	}
 }
 
 func (op *databaseOp) Create(ctx context.Context, plan Plan, request v1.NosqlCreateRequestAppliance) (*v1.NosqlAppliance, error) {
+	if _, ok := plan.Spec(); !ok {
+		return nil, NewError("Database.Create", fmt.Errorf("unknown plan %q", plan))
+	}
+	if op.client.Zone() != "" {
+		// 作成先のゾーンをクライアントのゾーンに揃える
+		zone, err := resolveZone(op.client, request.Remark.Nosql.Zone)
+		if err != nil {
+			return nil, NewError("Database.Create", err)
+		}
+		request.Remark.Nosql.Zone = zone
+	}
+
 	request.Class = "nosql"
 	request.Plan = v1.Plan{ID: plan.GetPlanID()}
 	request.ServiceClass = plan.GetServiceClass()
@@ -60,130 +80,229 @@ func (op *databaseOp) Create(ctx context.Context, plan Plan, request v1.NosqlCre
 	request.Remark.Nosql.Nodes = v1.NewOptNilInt(plan.GetNodes())
 	request.Remark.Nosql.Virtualcore = v1.NewOptNilInt(plan.GetVirtualCore())
 
-	res, err := op.client.CreateDB(ctx, &v1.NosqlCreateRequest{Appliance: request})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.CreateDBOperation, func(ctx context.Context) (v1.CreateDBRes, error) {
+		return op.client.CreateDB(ctx, &v1.NosqlCreateRequest{Appliance: request})
+	})
 	if err != nil {
-		return nil, NewAPIError("Database.Create", 0, err)
+		return nil, newAPIError(ctx, "Database.Create", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlCreateResponse:
 		return &p.Appliance, nil
 	case *v1.BadRequestResponse:
-		return nil, NewAPIError("Database.Create", 400, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.Create", 400, p)
 	case *v1.UnauthorizedResponse:
-		return nil, NewAPIError("Database.Create", 401, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.Create", 401, p)
 	case *v1.ConflictErrorResponse:
-		return nil, NewAPIError("Database.Create", 409, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.Create", 409, p)
 	case *v1.ServerErrorResponse:
-		return nil, NewAPIError("Database.Create", 500, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.Create", 500, p)
 	default:
-		return nil, NewAPIError("Database.Create", 0, nil)
+		return nil, newAPIError(ctx, "Database.Create", 0, nil)
 	}
 }
 
 func (op *databaseOp) Read(ctx context.Context, id string) (*v1.GetNosqlAppliance, error) {
-	res, err := op.client.GetDB(ctx, v1.GetDBParams{ApplianceID: id})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.GetDBOperation, func(ctx context.Context) (v1.GetDBRes, error) {
+		return op.client.GetDB(ctx, v1.GetDBParams{ApplianceID: id})
+	})
 	if err != nil {
-		return nil, NewAPIError("Database.Read", 0, err)
+		return nil, newAPIError(ctx, "Database.Read", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlGetResponse:
 		return &p.Appliance, nil
 	case *v1.BadRequestResponse:
-		return nil, NewAPIError("Database.Read", 400, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.Read", 400, p)
 	case *v1.UnauthorizedResponse:
-		return nil, NewAPIError("Database.Read", 401, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.Read", 401, p)
 	case *v1.NotFoundResponse:
-		return nil, NewAPIError("Database.Read", 404, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.Read", 404, p)
 	case *v1.ServerErrorResponse:
-		return nil, NewAPIError("Database.Read", 500, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.Read", 500, p)
 	default:
-		return nil, NewAPIError("Database.Read", 0, nil)
+		return nil, newAPIError(ctx, "Database.Read", 0, nil)
 	}
 }
 
 func (op *databaseOp) Update(ctx context.Context, id string, request v1.NosqlUpdateRequestAppliance) error {
-	res, err := op.client.UpdateDB(ctx, &v1.NosqlUpdateRequest{Appliance: request},
-		v1.UpdateDBParams{ApplianceID: id})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.UpdateDBOperation, func(ctx context.Context) (v1.UpdateDBRes, error) {
+		return op.client.UpdateDB(ctx, &v1.NosqlUpdateRequest{Appliance: request},
+			v1.UpdateDBParams{ApplianceID: id})
+	})
 	if err != nil {
-		return NewAPIError("Database.Update", 0, err)
+		return newAPIError(ctx, "Database.Update", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlSuccessResponse:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Database.Update", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.Update", 400, p)
 	case *v1.UnauthorizedResponse:
-		return NewAPIError("Database.Update", 401, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.Update", 401, p)
 	case *v1.NotFoundResponse:
-		return NewAPIError("Database.Update", 404, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.Update", 404, p)
 	case *v1.ConflictErrorResponse:
-		return NewAPIError("Database.Update", 409, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.Update", 409, p)
 	case *v1.ServerErrorResponse:
-		return NewAPIError("Database.Update", 500, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.Update", 500, p)
 	default:
-		return NewAPIError("Database.Update", 0, nil)
+		return newAPIError(ctx, "Database.Update", 0, nil)
 	}
 }
 
 func (op *databaseOp) Delete(ctx context.Context, id string) error {
-	res, err := op.client.DeleteDB(ctx, v1.DeleteDBParams{ApplianceID: id})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.DeleteDBOperation, func(ctx context.Context) (v1.DeleteDBRes, error) {
+		return op.client.DeleteDB(ctx, v1.DeleteDBParams{ApplianceID: id})
+	})
 	if err != nil {
-		return NewAPIError("Database.Delete", 0, err)
+		return newAPIError(ctx, "Database.Delete", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlSuccessResponse:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Database.Delete", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.Delete", 400, p)
 	case *v1.UnauthorizedResponse:
-		return NewAPIError("Database.Delete", 401, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.Delete", 401, p)
 	case *v1.NotFoundResponse:
-		return NewAPIError("Database.Delete", 404, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.Delete", 404, p)
 	case *v1.ConflictErrorResponse:
-		return NewAPIError("Database.Delete", 409, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.Delete", 409, p)
 	case *v1.ServerErrorResponse:
-		return NewAPIError("Database.Delete", 500, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.Delete", 500, p)
 	default:
-		return NewAPIError("Database.Delete", 0, nil)
+		return newAPIError(ctx, "Database.Delete", 0, nil)
 	}
 }
 
 func (op *databaseOp) ApplyChanges(ctx context.Context, id string) error {
-	res, err := op.client.UpdateConfigDB(ctx, v1.UpdateConfigDBParams{ApplianceID: id})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.UpdateConfigDBOperation, func(ctx context.Context) (v1.UpdateConfigDBRes, error) {
+		return op.client.UpdateConfigDB(ctx, v1.UpdateConfigDBParams{ApplianceID: id})
+	})
 	if err != nil {
-		return NewAPIError("Database.ApplyChanges", 0, err)
+		return newAPIError(ctx, "Database.ApplyChanges", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlIsOkResponse:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Database.ApplyChanges", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Database.ApplyChanges", 400, p)
 	default:
-		return NewAPIError("Database.ApplyChanges", 0, nil)
+		return newAPIError(ctx, "Database.ApplyChanges", 0, nil)
 	}
 }
 
 func (op *databaseOp) GetStatus(ctx context.Context, id string) (*v1.NosqlStatusResponseApplianceSettingsResponseNosql, error) {
-	res, err := op.client.ConfirmStatusDB(ctx, v1.ConfirmStatusDBParams{ApplianceID: id})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.ConfirmStatusDBOperation, func(ctx context.Context) (v1.ConfirmStatusDBRes, error) {
+		return op.client.ConfirmStatusDB(ctx, v1.ConfirmStatusDBParams{ApplianceID: id})
+	})
 	if err != nil {
-		return nil, NewAPIError("Database.GetStatus", 0, err)
+		return nil, newAPIError(ctx, "Database.GetStatus", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlStatusResponse:
 		return &p.Appliance.Value.SettingsResponse.Value.Nosql.Value, nil
 	case *v1.BadRequestResponse:
-		return nil, NewAPIError("Database.GetStatus", 400, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.GetStatus", 400, p)
 	case *v1.UnauthorizedResponse:
-		return nil, NewAPIError("Database.GetStatus", 401, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.GetStatus", 401, p)
 	case *v1.ServerErrorResponse:
-		return nil, NewAPIError("Database.GetStatus", 500, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Database.GetStatus", 500, p)
 	default:
-		return nil, NewAPIError("Database.GetStatus", 0, nil)
+		return nil, newAPIError(ctx, "Database.GetStatus", 0, nil)
+	}
+}
+
+// WaitUntilAvailable アプライアンスのAvailabilityがavailableになるまで待機する。
+// failedになった場合は*ApplianceFailedErrorを返す
+func (op *databaseOp) WaitUntilAvailable(ctx context.Context, id string, opts *WaitOptions) (_ *v1.GetNosqlAppliance, err error) {
+	ctx, span := startSpan(ctx, op.client, "Database.WaitUntilAvailable", id)
+	defer func() { endSpan(span, err) }()
+
+	var appliance *v1.GetNosqlAppliance
+	err = poll(ctx, metricsOf(op.client), "Database.WaitUntilAvailable", opts, func(ctx context.Context) (string, bool, error) {
+		res, err := op.Read(ctx, id)
+		if err != nil {
+			return "", false, err
+		}
+		appliance = res
+
+		availability := res.Availability.Value
+		switch availability {
+		case v1.AvailabilityAvailable:
+			return string(availability), true, nil
+		case v1.AvailabilityFailed:
+			return string(availability), false, NewError("Database.WaitUntilAvailable", &ApplianceFailedError{ID: id})
+		default:
+			return string(availability), false, nil
+		}
+	})
+	if err != nil {
+		return appliance, err
+	}
+	return appliance, nil
+}
+
+// UpdateAndApply 現在の設定をmutateで変更してUpdateし、他の更新と競合していなければApplyChangesする。
+// Updateの直前に再度読み出し、SettingsHashが変わっていればUpdateを行わずに*SettingsConflictErrorを返す。
+// Updateのリクエストには比較対象のハッシュを指定できないため、この確認とUpdateの間の競合は防げない。
+// そのためUpdate後にもSettingsHashが変わっており、かつ設定が変更内容と一致しない場合は
+// ApplyChangesを行わずに*SettingsConflictErrorを返す
+func (op *databaseOp) UpdateAndApply(ctx context.Context, id string, mutate func(*v1.NosqlSettings) error) (err error) {
+	ctx, span := startSpan(ctx, op.client, "Database.UpdateAndApply", id)
+	defer func() { endSpan(span, err) }()
+
+	current, err := op.Read(ctx, id)
+	if err != nil {
+		return err
 	}
+	hash := current.SettingsHash.Value
+
+	settings := NosqlSettingsFromGet(current.Settings.Value)
+	if err := mutate(&settings); err != nil {
+		return NewError("Database.UpdateAndApply", err)
+	}
+
+	latest, err := op.Read(ctx, id)
+	if err != nil {
+		return err
+	}
+	if latest.SettingsHash.Value != hash {
+		return NewError("Database.UpdateAndApply", &SettingsConflictError{
+			ID:           id,
+			ExpectedHash: hash,
+			ActualHash:   latest.SettingsHash.Value,
+		})
+	}
+
+	if err := op.Update(ctx, id, v1.NosqlUpdateRequestAppliance{ID: id, Settings: settings}); err != nil {
+		return err
+	}
+
+	updated, err := op.Read(ctx, id)
+	if err != nil {
+		return err
+	}
+	if updated.SettingsHash.Value != hash && !sameSettings(settings, NosqlSettingsFromGet(updated.Settings.Value)) {
+		return NewError("Database.UpdateAndApply", &SettingsConflictError{
+			ID:           id,
+			ExpectedHash: hash,
+			ActualHash:   updated.SettingsHash.Value,
+		})
+	}
+
+	return op.ApplyChanges(ctx, id)
 }
@@ -0,0 +1,147 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/sacloud/saclient-go"
+	"github.com/stretchr/testify/require"
+)
+
+const settingsApplianceJSON = `{"Appliance":{"ID":"123","SettingsHash":"%s","Settings":{"SourceNetwork":[%s],"Backup":{"Connect":"nfs://192.168.0.31/export","DayOfWeek":["sun"],"Time":"00:00","Rotate":2}}}}`
+
+func TestDatabaseOp_UpdateAndApply(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		if n <= 2 {
+			return http.StatusOK, fmt.Sprintf(settingsApplianceJSON, "hash1", "")
+		}
+		// 自身の更新でSettingsHashが変わったケース
+		return http.StatusOK, fmt.Sprintf(settingsApplianceJSON, "hash2", `"192.168.0.0/24"`)
+	})
+	var body string
+	api.Handle("PUT /appliance/123", func(_ int, r *http.Request) (int, string) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		return http.StatusOK, `{"Success":true,"is_ok":true}`
+	})
+	api.Handle("PUT /appliance/123/config", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"is_ok":true}`
+	})
+
+	err := NewDatabaseOp(api.Client()).UpdateAndApply(t.Context(), "123", func(s *v1.NosqlSettings) error {
+		s.SourceNetwork = append(s.SourceNetwork, "192.168.0.0/24")
+		return nil
+	})
+	assert.NoError(err)
+	assert.Contains(body, `"SourceNetwork":["192.168.0.0/24"]`)
+	assert.Contains(body, `"Connect":"nfs://192.168.0.31/export"`)
+	assert.Equal(1, api.Calls("PUT /appliance/123/config"))
+}
+
+func TestDatabaseOp_UpdateAndApply_Conflict(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		if n <= 2 {
+			return http.StatusOK, fmt.Sprintf(settingsApplianceJSON, "hash1", "")
+		}
+		// Updateの後、他のオペレータが別の変更を行ったケース
+		return http.StatusOK, fmt.Sprintf(settingsApplianceJSON, "hash2", `"10.0.0.0/8"`)
+	})
+	api.Handle("PUT /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Success":true,"is_ok":true}`
+	})
+
+	err := NewDatabaseOp(api.Client()).UpdateAndApply(t.Context(), "123", func(s *v1.NosqlSettings) error {
+		s.SourceNetwork = append(s.SourceNetwork, "192.168.0.0/24")
+		return nil
+	})
+	var conflict *SettingsConflictError
+	assert.ErrorAs(err, &conflict)
+	assert.Equal("hash1", conflict.ExpectedHash)
+	assert.Equal("hash2", conflict.ActualHash)
+	assert.Equal(1, api.Calls("PUT /appliance/123"))
+	assert.Equal(0, api.Calls("PUT /appliance/123/config"))
+}
+
+func TestDatabaseOp_UpdateAndApply_ConflictBeforeUpdate(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		if n == 1 {
+			return http.StatusOK, fmt.Sprintf(settingsApplianceJSON, "hash1", "")
+		}
+		// 最初の読み出しからUpdateまでの間に他のオペレータが変更したケース
+		return http.StatusOK, fmt.Sprintf(settingsApplianceJSON, "hash2", `"10.0.0.0/8"`)
+	})
+	api.Handle("PUT /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Success":true,"is_ok":true}`
+	})
+
+	err := NewDatabaseOp(api.Client()).UpdateAndApply(t.Context(), "123", func(s *v1.NosqlSettings) error {
+		s.SourceNetwork = append(s.SourceNetwork, "192.168.0.0/24")
+		return nil
+	})
+	var conflict *SettingsConflictError
+	assert.ErrorAs(err, &conflict)
+	assert.Equal("hash1", conflict.ExpectedHash)
+	assert.Equal("hash2", conflict.ActualHash)
+	assert.Equal(0, api.Calls("PUT /appliance/123"))
+	assert.Equal(0, api.Calls("PUT /appliance/123/config"))
+}
+
+func TestDatabaseOp_Read_APIError(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusNotFound, `{"is_fatal":true,"serial":"abcdef0123456789","status":"404 Not Found","error_code":"not_found","error_msg":"対象が見つかりません。"}`
+	})
+
+	_, err := NewDatabaseOp(api.Client()).Read(t.Context(), "123")
+	var apiErr *APIError
+	assert.ErrorAs(err, &apiErr)
+	assert.Equal(&APIError{
+		Op:            "Database.Read",
+		OperationName: v1.GetDBOperation,
+		StatusCode:    http.StatusNotFound,
+		IsFatal:       true,
+		Serial:        "abcdef0123456789",
+		Status:        "404 Not Found",
+		ErrorCode:     "not_found",
+		ErrorMsg:      "対象が見つかりません。",
+	}, apiErr)
+	assert.True(saclient.IsNotFoundError(err))
+	assert.True(IsNotFound(err))
+	assert.False(IsRetryable(err))
+	assert.Equal("nosql: Database.Read: API Error 404: 対象が見つかりません。", err.Error())
+}
+
+func TestDatabaseOp_Update_UnexpectedStatus(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("PUT /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusTeapot, `{}`
+	})
+
+	err := NewDatabaseOp(api.Client()).Update(t.Context(), "123", v1.NosqlUpdateRequestAppliance{})
+	assert.True(IsUnexpectedResponse(err))
+	assert.False(IsServerError(err))
+
+	var nosqlErr *Error
+	assert.ErrorAs(err, &nosqlErr)
+	assert.Equal(v1.UpdateDBOperation, nosqlErr.OperationName())
+}
@@ -0,0 +1,186 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/sacloud/saclient-go"
+)
+
+const (
+	// DefaultDiagnosticsMaxBodyBytes 診断情報として保持するレスポンスボディの最大バイト数
+	DefaultDiagnosticsMaxBodyBytes = 4096
+	// DefaultRequestIDHeader リクエストIDを取得するヘッダー
+	DefaultRequestIDHeader = "X-Request-Id"
+
+	redactedValue = "[REDACTED]"
+)
+
+// DefaultDiagnosticsHeaders 診断情報として保持するヘッダー
+var DefaultDiagnosticsHeaders = []string{"Content-Type", "Date", "Retry-After", DefaultRequestIDHeader}
+
+// DefaultRedactKeys 診断情報のレスポンスボディで値を伏せるJSONのキー
+var DefaultRedactKeys = []string{"Password", "Token", "Secret", "AccessToken", "AccessTokenSecret"}
+
+// DiagnosticsOptions 想定外のレスポンスを受け取った際に保持する診断情報の設定。ゼロ値の項目はデフォルト値を使う
+type DiagnosticsOptions struct {
+	// Headers 保持するヘッダー
+	Headers []string
+	// RequestIDHeader リクエストIDを取得するヘッダー
+	RequestIDHeader string
+	// MaxBodyBytes 保持するレスポンスボディの最大バイト数
+	MaxBodyBytes int
+	// RedactKeys 値を伏せるJSONのキー(大文字小文字は区別しない)
+	RedactKeys []string
+}
+
+// Diagnostics APIの呼び出しに失敗した際のレスポンスの診断情報
+type Diagnostics struct {
+	StatusCode int
+	Header     http.Header
+	RequestID  string
+	// Body 秘匿情報を伏せて切り詰めたレスポンスボディ
+	Body string
+	// Truncated Bodyが切り詰められたかどうか
+	Truncated bool
+}
+
+func (d *Diagnostics) String() string {
+	s := fmt.Sprintf("status: %d", d.StatusCode)
+	if d.RequestID != "" {
+		s += ", request id: " + d.RequestID
+	}
+	return s
+}
+
+// WithDiagnostics API呼び出しに失敗した際にレスポンスの診断情報をエラーに添付する。
+// 添付された診断情報は(*Error).Diagnosticsで取得できる。
+// 2xx以外のレスポンスはボディを読み出して保持し、2xxのレスポンスはボディをそのまま流してデコードの失敗に備えて先頭のみを保持する
+func WithDiagnostics(opts *DiagnosticsOptions) ClientOption {
+	o := DiagnosticsOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Headers == nil {
+		o.Headers = DefaultDiagnosticsHeaders
+	}
+	if o.RequestIDHeader == "" {
+		o.RequestIDHeader = DefaultRequestIDHeader
+	}
+	if o.MaxBodyBytes <= 0 {
+		o.MaxBodyBytes = DefaultDiagnosticsMaxBodyBytes
+	}
+	if o.RedactKeys == nil {
+		o.RedactKeys = DefaultRedactKeys
+	}
+	keys := make([]string, len(o.RedactKeys))
+	for i, k := range o.RedactKeys {
+		keys[i] = regexp.QuoteMeta(k)
+	}
+	redact := regexp.MustCompile(`(?i)("(?:` + strings.Join(keys, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+
+	diagnose := func(res *http.Response, body []byte, truncated bool) *Diagnostics {
+		d := &Diagnostics{
+			StatusCode: res.StatusCode,
+			Header:     http.Header{},
+			RequestID:  res.Header.Get(o.RequestIDHeader),
+		}
+		for _, h := range o.Headers {
+			if v := res.Header.Values(h); len(v) > 0 {
+				d.Header[http.CanonicalHeaderKey(h)] = v
+			}
+		}
+		body = redact.ReplaceAll(body, []byte(`${1}"`+redactedValue+`"`))
+		if len(body) > o.MaxBodyBytes {
+			body = body[:o.MaxBodyBytes]
+			truncated = true
+		}
+		d.Body = string(body)
+		d.Truncated = truncated
+		return d
+	}
+
+	return func(c *clientConfig) {
+		c.middlewares = append(c.middlewares, func(req *http.Request, pull func() (saclient.Middleware, bool)) (*http.Response, error) {
+			next, ok := pull()
+			if !ok {
+				return nil, saclient.NewErrorf("no next middleware to pull")
+			}
+			res, err := next(req, pull)
+			rec, ok := req.Context().Value(diagnosticsKey{}).(*diagnosticsRecorder)
+			if err != nil || res == nil || !ok {
+				return res, err
+			}
+
+			if res.StatusCode >= 200 && res.StatusCode < 300 {
+				// 成功時はボディをそのまま流し、デコードに失敗した場合に備えて先頭だけを保持する。
+				// 伏せ字の置き換えで長さが変わるため、MaxBodyBytesの2倍まで保持する
+				capture := &captureBody{ReadCloser: res.Body, limit: 2 * o.MaxBodyBytes}
+				res.Body = capture
+				rec.diagnostics = nil
+				rec.pending = func() *Diagnostics { return diagnose(res, capture.buf.Bytes(), capture.overflow) }
+				return res, nil
+			}
+
+			body, err := io.ReadAll(res.Body)
+			res.Body.Close() //nolint:errcheck
+			if err != nil {
+				return nil, err
+			}
+			res.Body = io.NopCloser(bytes.NewReader(body))
+			rec.diagnostics = diagnose(res, body, false)
+			rec.pending = nil
+			return res, nil
+		})
+	}
+}
+
+// captureBody 読み出されたボディの先頭limitバイトを保持する
+type captureBody struct {
+	io.ReadCloser
+	limit    int
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if rest := b.limit - b.buf.Len(); rest > 0 {
+		b.buf.Write(p[:min(n, rest)])
+		b.overflow = b.overflow || n > rest
+	} else if n > 0 {
+		b.overflow = true
+	}
+	return n, err
+}
+
+type diagnosticsKey struct{}
+
+type diagnosticsRecorder struct {
+	diagnostics *Diagnostics
+	// pending 成功時のレスポンスの診断情報を必要になった時点で作成する
+	pending func() *Diagnostics
+}
+
+// withDiagnostics WithDiagnosticsが有効な場合にレスポンスを記録できるようにする
+func withDiagnostics(ctx context.Context) context.Context {
+	return context.WithValue(ctx, diagnosticsKey{}, &diagnosticsRecorder{})
+}
+
+func diagnosticsFromContext(ctx context.Context) *Diagnostics {
+	if rec, ok := ctx.Value(diagnosticsKey{}).(*diagnosticsRecorder); ok {
+		if rec.diagnostics == nil && rec.pending != nil {
+			rec.diagnostics = rec.pending()
+		}
+		return rec.diagnostics
+	}
+	return nil
+}
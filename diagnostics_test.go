@@ -0,0 +1,99 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/sacloud/nosql-api-go"
+	"github.com/sacloud/saclient-go"
+	"github.com/stretchr/testify/require"
+)
+
+func newDiagnosticsServer(t *testing.T, status int, body string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-123")
+		w.Header().Set("X-Internal", "secret")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	var theClient saclient.Client
+	require.NoError(t, theClient.SetEnviron([]string{"SAKURA_RATE_LIMIT=1000"}))
+	client, err := NewClientWithAPIRootURL(&theClient, server.URL, WithDiagnostics(&DiagnosticsOptions{MaxBodyBytes: 64}))
+	require.NoError(t, err)
+	return client
+}
+
+func TestWithDiagnostics_UnexpectedStatus(t *testing.T) {
+	assert := require.New(t)
+
+	client := newDiagnosticsServer(t, http.StatusTeapot, `{"Password":"p@ss\"word","message":"`+strings.Repeat("x", 100)+`"}`)
+	_, err := NewDatabaseOp(client).List(t.Context())
+	assert.True(IsUnexpectedResponse(err))
+
+	var nosqlErr *Error
+	assert.ErrorAs(err, &nosqlErr)
+	diag := nosqlErr.Diagnostics()
+	assert.NotNil(diag)
+	assert.Equal(http.StatusTeapot, diag.StatusCode)
+	assert.Equal("req-123", diag.RequestID)
+	assert.Equal("application/json", diag.Header.Get("Content-Type"))
+	assert.Empty(diag.Header.Get("X-Internal"))
+	assert.True(diag.Truncated)
+	assert.Len(diag.Body, 64)
+	assert.True(strings.HasPrefix(diag.Body, `{"Password":"[REDACTED]","message":"xxx`), diag.Body)
+	assert.Contains(err.Error(), "(status: 418, request id: req-123)")
+}
+
+func TestWithDiagnostics_DecodeError(t *testing.T) {
+	assert := require.New(t)
+
+	client := newDiagnosticsServer(t, http.StatusOK, `{"Appliance":{"ID":123,"Settings":{"Password":"secret"}}`)
+	_, err := NewDatabaseOp(client).Read(t.Context(), "123")
+	assert.True(IsUnexpectedResponse(err))
+
+	var nosqlErr *Error
+	assert.ErrorAs(err, &nosqlErr)
+	diag := nosqlErr.Diagnostics()
+	assert.NotNil(diag)
+	assert.Equal(http.StatusOK, diag.StatusCode)
+	assert.Equal(`{"Appliance":{"ID":123,"Settings":{"Password":"[REDACTED]"}}`, diag.Body)
+}
+
+func TestWithDiagnostics_DecodeErrorLargeBody(t *testing.T) {
+	assert := require.New(t)
+
+	client := newDiagnosticsServer(t, http.StatusOK, `{"Appliance":{"ID":123,"Description":"`+strings.Repeat("x", 1000)+`"}}`)
+	_, err := NewDatabaseOp(client).Read(t.Context(), "123")
+	assert.True(IsUnexpectedResponse(err))
+
+	var nosqlErr *Error
+	assert.ErrorAs(err, &nosqlErr)
+	diag := nosqlErr.Diagnostics()
+	assert.NotNil(diag)
+	assert.True(diag.Truncated)
+	assert.Equal(`{"Appliance":{"ID":123,"Description":"xxx`, diag.Body[:41])
+	assert.Len(diag.Body, 64)
+}
+
+func TestWithoutDiagnostics(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance", func(int, *http.Request) (int, string) {
+		return http.StatusTeapot, `{}`
+	})
+
+	_, err := NewDatabaseOp(api.Client()).List(t.Context())
+	var nosqlErr *Error
+	assert.ErrorAs(err, &nosqlErr)
+	assert.Nil(nosqlErr.Diagnostics())
+}
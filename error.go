@@ -3,14 +3,47 @@
 
 package nosql
 
-import "github.com/sacloud/saclient-go"
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/ogen-go/ogen/ogenerrors"
+	"github.com/ogen-go/ogen/validate"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/sacloud/saclient-go"
+)
+
+// API呼び出しのエラーの分類。errors.Isで判定できる
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrConflict           = errors.New("conflict")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrServerError        = errors.New("server error")
+	ErrUnexpectedResponse = errors.New("unexpected response")
+)
 
 type Error struct {
 	msg string
 	err error
+
+	// 以下はNewAPIErrorで作成された場合のみ設定される
+	api         bool
+	code        int
+	unexpected  bool
+	operation   v1.OperationName
+	diagnostics *Diagnostics
 }
 
 func (e *Error) Error() string {
+	if e.diagnostics != nil {
+		return e.message() + " (" + e.diagnostics.String() + ")"
+	}
+	return e.message()
+}
+
+func (e *Error) message() string {
 	if e.msg != "" {
 		if e.err != nil {
 			return "nosql: " + e.msg + ": " + e.err.Error()
@@ -30,6 +63,188 @@ func NewError(msg string, err error) *Error {
 	return &Error{msg: msg, err: err}
 }
 
+// Is errors.Isで ErrNotFound などの分類と比較する
+func (e *Error) Is(target error) bool {
+	if !e.api {
+		return false
+	}
+	switch target {
+	case ErrNotFound:
+		return e.code == http.StatusNotFound
+	case ErrConflict:
+		return e.code == http.StatusConflict
+	case ErrUnauthorized:
+		return e.code == http.StatusUnauthorized || e.code == http.StatusForbidden
+	case ErrServerError:
+		return e.code >= http.StatusInternalServerError
+	case ErrUnexpectedResponse:
+		return e.unexpected
+	}
+	return false
+}
+
+// OperationName 失敗したAPIのオペレーション名(v1.GetDBOperationなど)を返す。API呼び出し以外のエラーの場合は空になる
+func (e *Error) OperationName() v1.OperationName {
+	if e.operation != "" {
+		return e.operation
+	}
+	var inner *Error
+	if errors.As(e.err, &inner) {
+		return inner.OperationName()
+	}
+	return ""
+}
+
+// Diagnostics WithDiagnosticsが有効な場合に添付されたレスポンスの診断情報を返す
+func (e *Error) Diagnostics() *Diagnostics {
+	if e.diagnostics != nil {
+		return e.diagnostics
+	}
+	var inner *Error
+	if errors.As(e.err, &inner) {
+		return inner.Diagnostics()
+	}
+	return nil
+}
+
 func NewAPIError(method string, code int, err error) *Error {
-	return &Error{msg: method, err: saclient.NewError(code, "", err)}
+	e := &Error{msg: method, api: true, code: code, operation: operationNames[method]}
+	if code == 0 {
+		// 想定外のステータスコードやレスポンスのデコード失敗
+		var statusErr *validate.UnexpectedStatusCodeError
+		var bodyErr *ogenerrors.DecodeBodyError
+		var ctErr *validate.InvalidContentTypeError
+		switch {
+		case err == nil:
+			e.unexpected = true
+		case errors.As(err, &statusErr):
+			e.unexpected = true
+			e.code = statusErr.StatusCode
+		case errors.As(err, &bodyErr), errors.As(err, &ctErr):
+			e.unexpected = true
+		}
+	}
+	e.err = saclient.NewError(e.code, "", err)
+	return e
+}
+
+// newAPIError NewAPIErrorにwithDiagnosticsで記録したレスポンスの診断情報を添付する
+func newAPIError(ctx context.Context, method string, code int, err error) *Error {
+	e := NewAPIError(method, code, err)
+	e.diagnostics = diagnosticsFromContext(ctx)
+	return e
+}
+
+// operationNames 各メソッドが呼び出すAPIのオペレーション名
+var operationNames = map[string]v1.OperationName{
+	"Database.List":           v1.ListDBOperation,
+	"Database.Create":         v1.CreateDBOperation,
+	"Database.Read":           v1.GetDBOperation,
+	"Database.Update":         v1.UpdateDBOperation,
+	"Database.Delete":         v1.DeleteDBOperation,
+	"Database.ApplyChanges":   v1.UpdateConfigDBOperation,
+	"Database.GetStatus":      v1.ConfirmStatusDBOperation,
+	"Instance.GetVersion":     v1.GetVersionOperation,
+	"Instance.UpgradeVersion": v1.PutVersionOperation,
+	"Instance.GetParameters":  v1.GetParameterOperation,
+	"Instance.SetParameters":  v1.PutParameterOperation,
+	"Instance.GetNodeHealth":  v1.GetNoSQLNodeHealthOperation,
+	"Instance.AddNodes":       v1.CreateDBOperation,
+	"Instance.Recover":        v1.RecoverNoSQLNodeOperation,
+	"Instance.Repair":         v1.PostNoSQLRepairOperation,
+	"Instance.Start":          v1.PutAppliancePowerOperation,
+	"Instance.Stop":           v1.DeleteAppliancePowerOperation,
+	"Backup.List":             v1.GetBackupByApplianceIDOperation,
+	"Backup.Create":           v1.CreateBackupOperation,
+	"Backup.Restore":          v1.RestoreBackupOperation,
+	"Backup.Delete":           v1.DeleteBackupOperation,
+}
+
+// IsNotFound 対象が存在しない(404)ことを示すエラーか
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict 他のジョブの実行中などで競合した(409)ことを示すエラーか
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsUnauthorized 認証・認可に失敗した(401/403)ことを示すエラーか
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+// IsServerError サーバ側のエラー(5xx)か
+func IsServerError(err error) bool {
+	return errors.Is(err, ErrServerError)
+}
+
+// IsUnexpectedResponse 想定外のステータスコードや解釈できないレスポンスが返されたことを示すエラーか
+func IsUnexpectedResponse(err error) bool {
+	return errors.Is(err, ErrUnexpectedResponse)
+}
+
+// IsRetryable 時間をおいて再実行すれば成功する可能性のあるエラーか
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if IsConflict(err) || IsServerError(err) {
+		return true
+	}
+	var e *Error
+	if errors.As(err, &e) && e.api && e.code == http.StatusTooManyRequests {
+		return true
+	}
+	var netErr *net.OpError
+	return errors.As(err, &netErr)
+}
+
+// APIError APIが返したエラーレスポンスの内容。errors.Asで取り出せる
+type APIError struct {
+	// Op エラーとなった操作名(Database.Readなど)
+	Op string
+	// OperationName 呼び出したAPIのオペレーション名
+	OperationName v1.OperationName
+	// StatusCode HTTPステータスコード
+	StatusCode int
+	IsFatal    bool
+	// Serial 追跡コード
+	Serial    string
+	Status    string
+	ErrorCode string
+	ErrorMsg  string
+}
+
+func (e *APIError) Error() string {
+	if e.ErrorMsg != "" {
+		return e.ErrorMsg
+	}
+	if e.ErrorCode != "" {
+		return "error_code: " + e.ErrorCode
+	}
+	return "unknown error"
+}
+
+// errorResponse BadRequestResponseなどのエラーレスポンスに共通するメソッド
+type errorResponse interface {
+	GetIsFatal() v1.OptBool
+	GetSerial() v1.OptString
+	GetStatus() v1.OptString
+	GetErrorCode() v1.OptString
+	GetErrorMsg() v1.OptString
+}
+
+func newAPIResponseError(ctx context.Context, method string, code int, res errorResponse) *Error {
+	return newAPIError(ctx, method, code, &APIError{
+		Op:            method,
+		OperationName: operationNames[method],
+		StatusCode:    code,
+		IsFatal:       res.GetIsFatal().Value,
+		Serial:        res.GetSerial().Value,
+		Status:        res.GetStatus().Value,
+		ErrorCode:     res.GetErrorCode().Value,
+		ErrorMsg:      res.GetErrorMsg().Value,
+	})
 }
@@ -0,0 +1,194 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// ErrorCatalogEntry APIのエラーに対する説明と対処方法。
+// Operation・StatusCode・ErrorCodeはゼロ値の場合は全てに一致し、より多くの項目が一致するエントリが優先される
+type ErrorCatalogEntry struct {
+	Operation  v1.OperationName `json:"operation,omitempty" yaml:"operation,omitempty"`
+	StatusCode int              `json:"status_code,omitempty" yaml:"status_code,omitempty"`
+	ErrorCode  string           `json:"error_code,omitempty" yaml:"error_code,omitempty"`
+
+	// ID エラーの種類を示す安定した識別子
+	ID          string `json:"id" yaml:"id"`
+	Explanation string `json:"explanation" yaml:"explanation"`
+	Remediation string `json:"remediation" yaml:"remediation"`
+}
+
+var defaultErrorCatalog = []ErrorCatalogEntry{
+	{
+		ErrorCode:   "limit_count_in_zone",
+		ID:          "zone_limit_exceeded",
+		Explanation: "The number of resources in the zone has reached the limit of the account.",
+		Remediation: "Delete unused resources in the zone or ask support to raise the limit.",
+	},
+	{
+		Operation:   v1.CreateDBOperation,
+		StatusCode:  http.StatusConflict,
+		ID:          "create_conflict",
+		Explanation: "The appliance could not be created because it conflicts with the current state of existing resources.",
+		Remediation: "Check error_msg for the conflicting resource, for example a busy switch or an address already in use, and retry after resolving it.",
+	},
+	{
+		StatusCode:  http.StatusConflict,
+		ID:          "appliance_busy",
+		Explanation: "Another job is running on the appliance.",
+		Remediation: "Retry after the running job completes. JobTracker.WaitForJobs or WithRetryPolicy can do this automatically.",
+	},
+	{
+		Operation:   v1.UpdateConfigDBOperation,
+		StatusCode:  http.StatusBadRequest,
+		ID:          "settings_not_applicable",
+		Explanation: "The updated settings could not be applied to the appliance.",
+		Remediation: "Check that the appliance is up and that the settings passed Update; then call ApplyChanges again.",
+	},
+	{
+		StatusCode:  http.StatusBadRequest,
+		ID:          "invalid_request",
+		Explanation: "The API rejected the request parameters.",
+		Remediation: "Fix the parameter reported in error_msg and send the request again.",
+	},
+	{
+		StatusCode:  http.StatusUnauthorized,
+		ID:          "unauthorized",
+		Explanation: "The API key is missing or invalid, or it has no permission for the resource.",
+		Remediation: "Check the access token and secret and the permissions of the API key.",
+	},
+	{
+		StatusCode:  http.StatusNotFound,
+		ID:          "resource_not_found",
+		Explanation: "The appliance or backup does not exist in the zone of the client.",
+		Remediation: "Check the ID and the zone. The resource may have been deleted.",
+	},
+	{
+		StatusCode:  http.StatusInternalServerError,
+		ID:          "internal_server_error",
+		Explanation: "The API failed with an internal error.",
+		Remediation: "Retry later. If the error persists, contact support with the serial of the error.",
+	},
+	{
+		StatusCode:  http.StatusServiceUnavailable,
+		ID:          "service_unavailable",
+		Explanation: "The API is temporarily unavailable, for example during maintenance.",
+		Remediation: "Retry later and check the maintenance information of the zone.",
+	},
+}
+
+var errorCatalog atomic.Pointer[[]ErrorCatalogEntry]
+
+func currentErrorCatalog() []ErrorCatalogEntry {
+	if c := errorCatalog.Load(); c != nil {
+		return *c
+	}
+	return defaultErrorCatalog
+}
+
+// ErrorCatalog 現在のエラーカタログを返す
+func ErrorCatalog() []ErrorCatalogEntry {
+	catalog := currentErrorCatalog()
+	ret := make([]ErrorCatalogEntry, len(catalog))
+	copy(ret, catalog)
+	return ret
+}
+
+// ExtendErrorCatalog エラーカタログに追加する。Operation・StatusCode・ErrorCodeが同じものは置き換える
+func ExtendErrorCatalog(entries []ErrorCatalogEntry) error {
+	var errs []error
+	for _, e := range entries {
+		if e.ID == "" {
+			errs = append(errs, fmt.Errorf("entry (operation: %q, status: %d, error_code: %q): id is required", e.Operation, e.StatusCode, e.ErrorCode))
+		}
+	}
+	if len(errs) > 0 {
+		return NewError("invalid error catalog", errors.Join(errs...))
+	}
+
+	merged := ErrorCatalog()
+	for _, e := range entries {
+		replaced := false
+		for i := range merged {
+			if merged[i].Operation == e.Operation && merged[i].StatusCode == e.StatusCode && merged[i].ErrorCode == e.ErrorCode {
+				merged[i] = e
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, e)
+		}
+	}
+	errorCatalog.Store(&merged)
+	return nil
+}
+
+// ResetErrorCatalog エラーカタログを組み込みのものに戻す
+func ResetErrorCatalog() {
+	errorCatalog.Store(nil)
+}
+
+// LookupErrorCatalog オペレーション・HTTPステータスコード・error_codeに最も良く一致するエントリを返す
+func LookupErrorCatalog(operation v1.OperationName, statusCode int, errorCode string) (ErrorCatalogEntry, bool) {
+	var found ErrorCatalogEntry
+	best := -1
+	for _, e := range currentErrorCatalog() {
+		score := 0
+		switch {
+		case e.ErrorCode == "":
+		case e.ErrorCode == errorCode:
+			score += 4
+		default:
+			continue
+		}
+		switch {
+		case e.Operation == "":
+		case e.Operation == operation:
+			score += 2
+		default:
+			continue
+		}
+		switch {
+		case e.StatusCode == 0:
+		case e.StatusCode == statusCode:
+			score++
+		default:
+			continue
+		}
+		if score > best {
+			found, best = e, score
+		}
+	}
+	return found, best >= 0
+}
+
+// Explain エラーカタログからエラーの説明と対処方法を返す
+func (e *APIError) Explain() (ErrorCatalogEntry, bool) {
+	return LookupErrorCatalog(e.OperationName, e.StatusCode, e.ErrorCode)
+}
+
+// Explain エラーカタログからAPIのエラーの説明と対処方法を返す。API呼び出し以外のエラーの場合はfalseを返す
+func (e *Error) Explain() (ErrorCatalogEntry, bool) {
+	var apiErr *APIError
+	if errors.As(e, &apiErr) {
+		return apiErr.Explain()
+	}
+	var inner *Error
+	for err := error(e); errors.As(err, &inner); err = inner.err {
+		if inner.api {
+			if inner.code == 0 {
+				return ErrorCatalogEntry{}, false
+			}
+			return LookupErrorCatalog(inner.operation, inner.code, "")
+		}
+	}
+	return ErrorCatalogEntry{}, false
+}
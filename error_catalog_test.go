@@ -0,0 +1,83 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupErrorCatalog(t *testing.T) {
+	cases := []struct {
+		name       string
+		operation  v1.OperationName
+		statusCode int
+		errorCode  string
+		wantID     string
+	}{
+		{name: "error code", operation: v1.CreateDBOperation, statusCode: 409, errorCode: "limit_count_in_zone", wantID: "zone_limit_exceeded"},
+		{name: "operation and status", operation: v1.CreateDBOperation, statusCode: 409, errorCode: "unknown", wantID: "create_conflict"},
+		{name: "status", operation: v1.UpdateDBOperation, statusCode: 409, wantID: "appliance_busy"},
+		{name: "not found", operation: v1.GetDBOperation, statusCode: 404, errorCode: "not_found", wantID: "resource_not_found"},
+		{name: "unknown", operation: v1.GetDBOperation, statusCode: 418},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, ok := LookupErrorCatalog(tc.operation, tc.statusCode, tc.errorCode)
+			require.Equal(t, tc.wantID != "", ok)
+			require.Equal(t, tc.wantID, entry.ID)
+		})
+	}
+}
+
+func TestExtendErrorCatalog(t *testing.T) {
+	assert := require.New(t)
+	t.Cleanup(ResetErrorCatalog)
+	n := len(ErrorCatalog())
+
+	assert.Error(ExtendErrorCatalog([]ErrorCatalogEntry{{StatusCode: 409}}))
+	assert.NoError(ExtendErrorCatalog([]ErrorCatalogEntry{
+		{Operation: v1.RestoreBackupOperation, StatusCode: 409, ID: "restore_in_progress"},
+		{StatusCode: 409, ID: "busy", Remediation: "wait"},
+	}))
+
+	entry, _ := LookupErrorCatalog(v1.RestoreBackupOperation, 409, "")
+	assert.Equal("restore_in_progress", entry.ID)
+	entry, _ = LookupErrorCatalog(v1.DeleteDBOperation, 409, "")
+	assert.Equal("busy", entry.ID)
+	// StatusCodeのみのエントリは組み込みのappliance_busyを置き換える
+	assert.Len(ErrorCatalog(), n+1)
+
+	ResetErrorCatalog()
+	entry, _ = LookupErrorCatalog(v1.DeleteDBOperation, 409, "")
+	assert.Equal("appliance_busy", entry.ID)
+}
+
+func TestError_Explain(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("PUT /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusConflict, conflictJSON
+	})
+
+	err := NewDatabaseOp(api.Client()).Update(t.Context(), "123", v1.NosqlUpdateRequestAppliance{})
+	var nosqlErr *Error
+	assert.ErrorAs(err, &nosqlErr)
+	entry, ok := nosqlErr.Explain()
+	assert.True(ok)
+	assert.Equal("appliance_busy", entry.ID)
+
+	entry, ok = NewError("Database.UpdateAndApply", NewAPIError("Database.Read", 404, errors.New("not found"))).Explain()
+	assert.True(ok)
+	assert.Equal("resource_not_found", entry.ID)
+
+	_, ok = NewError("msg", nil).Explain()
+	assert.False(ok)
+}
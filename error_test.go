@@ -4,9 +4,14 @@
 package nosql
 
 import (
+	"context"
 	"errors"
+	"net"
+	"slices"
 	"testing"
 
+	"github.com/ogen-go/ogen/validate"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
 	client "github.com/sacloud/saclient-go"
 )
 
@@ -72,3 +77,86 @@ func TestNewAPIError(t *testing.T) {
 		t.Errorf("IsNotFoundError is false for NewAPIError with 503")
 	}
 }
+
+func TestError_Is(t *testing.T) {
+	wrap := func(err error) error { return NewError("Database.UpdateAndApply", err) }
+
+	tests := []struct {
+		name      string
+		err       error
+		sentinels []error
+		retryable bool
+	}{
+		{
+			name:      "not found",
+			err:       NewAPIError("Database.Read", 404, errors.New("not found")),
+			sentinels: []error{ErrNotFound},
+		},
+		{
+			name:      "conflict",
+			err:       wrap(NewAPIError("Database.Update", 409, errors.New("busy"))),
+			sentinels: []error{ErrConflict},
+			retryable: true,
+		},
+		{
+			name:      "unauthorized",
+			err:       NewAPIError("Database.List", 401, errors.New("unauthorized")),
+			sentinels: []error{ErrUnauthorized},
+		},
+		{
+			name:      "server error",
+			err:       NewAPIError("Backup.Create", 500, errors.New("error")),
+			sentinels: []error{ErrServerError},
+			retryable: true,
+		},
+		{
+			name:      "unexpected response",
+			err:       NewAPIError("Instance.Start", 0, nil),
+			sentinels: []error{ErrUnexpectedResponse},
+		},
+		{
+			name:      "undocumented status",
+			err:       NewAPIError("Instance.Start", 0, &validate.UnexpectedStatusCodeError{StatusCode: 503}),
+			sentinels: []error{ErrUnexpectedResponse, ErrServerError},
+			retryable: true,
+		},
+		{
+			name:      "network error",
+			err:       NewAPIError("Database.List", 0, &net.OpError{Op: "dial", Err: errors.New("connection refused")}),
+			retryable: true,
+		},
+		{
+			name: "canceled",
+			err:  NewAPIError("Database.List", 0, context.Canceled),
+		},
+		{
+			name: "not an API error",
+			err:  NewError("msg", nil),
+		},
+	}
+
+	all := []error{ErrNotFound, ErrConflict, ErrUnauthorized, ErrServerError, ErrUnexpectedResponse}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, sentinel := range all {
+				want := slices.Contains(tt.sentinels, sentinel)
+				if got := errors.Is(tt.err, sentinel); got != want {
+					t.Errorf("errors.Is(%v) = %v, want %v", sentinel, got, want)
+				}
+			}
+			if got := IsRetryable(tt.err); got != tt.retryable {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestError_OperationName(t *testing.T) {
+	err := NewError("Database.UpdateAndApply", NewAPIError("Database.Update", 409, nil))
+	if got := err.OperationName(); got != v1.UpdateDBOperation {
+		t.Errorf("OperationName() = %q, want %q", got, v1.UpdateDBOperation)
+	}
+	if got := NewError("msg", nil).OperationName(); got != "" {
+		t.Errorf("OperationName() = %q, want empty", got)
+	}
+}
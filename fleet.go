@@ -0,0 +1,188 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/sacloud/saclient-go"
+)
+
+// DefaultFleetConcurrency 同時にAPIを呼び出すゾーン数のデフォルト値
+const DefaultFleetConcurrency = 4
+
+// FleetOptions Fleetの設定
+type FleetOptions struct {
+	// Concurrency 同時にAPIを呼び出すゾーン数。0の場合はDefaultFleetConcurrency
+	Concurrency int
+	// ClientOptions 各ゾーンのクライアントの作成時に指定するオプション
+	ClientOptions []ClientOption
+	// OpOptions 各ゾーンのオペレーションの作成時に指定するオプション
+	OpOptions []OpOption
+}
+
+// Fleet 複数ゾーンのNoSQLアプライアンスをまとめて扱うクライアント
+type Fleet struct {
+	zones       []string
+	clients     map[string]*Client
+	concurrency int
+	opOptions   []OpOption
+}
+
+// FleetAppliance ゾーンを記録したアプライアンス
+type FleetAppliance struct {
+	Zone      string
+	Appliance v1.GetNosqlAppliance
+}
+
+// ZoneError ゾーン毎のエラー
+type ZoneError struct {
+	Zone string
+	Err  error
+}
+
+func (e *ZoneError) Error() string {
+	return e.Zone + ": " + e.Err.Error()
+}
+
+func (e *ZoneError) Unwrap() error {
+	return e.Err
+}
+
+// FleetError 一部のゾーンで失敗したことを示すエラー。失敗したゾーン毎のエラーを持つ
+type FleetError struct {
+	Errors []*ZoneError
+}
+
+func (e *FleetError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d zone(s) failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *FleetError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// NewFleet 各ゾーンのクライアントをNewClientForZoneで作成し、Fleetを作成する
+func NewFleet(client saclient.ClientAPI, zones []string, opts *FleetOptions) (*Fleet, error) {
+	if opts == nil {
+		opts = &FleetOptions{}
+	}
+	clients := make(map[string]*Client, len(zones))
+	for _, zone := range zones {
+		c, err := NewClientForZone(client, zone, opts.ClientOptions...)
+		if err != nil {
+			return nil, err
+		}
+		clients[zone] = c
+	}
+	return NewFleetWithClients(clients, opts)
+}
+
+// NewFleetWithClients ゾーン名をキーとするクライアントからFleetを作成する。opts.ClientOptionsは使われない。
+// クライアントのゾーン(Client.Zone)がキーと異なる場合はエラーを返す
+func NewFleetWithClients(clients map[string]*Client, opts *FleetOptions) (*Fleet, error) {
+	if len(clients) == 0 {
+		return nil, NewError("Fleet", errors.New("no zones"))
+	}
+	if opts == nil {
+		opts = &FleetOptions{}
+	}
+	f := &Fleet{
+		clients:     make(map[string]*Client, len(clients)),
+		concurrency: opts.Concurrency,
+		opOptions:   opts.OpOptions,
+	}
+	if f.concurrency <= 0 {
+		f.concurrency = DefaultFleetConcurrency
+	}
+	for zone, c := range clients {
+		if err := ValidateZone(zone); err != nil {
+			return nil, err
+		}
+		if c.Zone() != zone {
+			return nil, NewError("Fleet", fmt.Errorf("the client for zone %q connects to zone %q", zone, c.Zone()))
+		}
+		f.zones = append(f.zones, zone)
+		f.clients[zone] = c
+	}
+	slices.Sort(f.zones)
+	return f, nil
+}
+
+// Zones Fleetが扱うゾーンを返す
+func (f *Fleet) Zones() []string {
+	return slices.Clone(f.zones)
+}
+
+// Client ゾーンのクライアントを返す
+func (f *Fleet) Client(zone string) (*Client, bool) {
+	c, ok := f.clients[zone]
+	return c, ok
+}
+
+// Database ゾーンのDatabaseAPIを返す
+func (f *Fleet) Database(zone string) (DatabaseAPI, bool) {
+	c, ok := f.clients[zone]
+	if !ok {
+		return nil, false
+	}
+	return NewDatabaseOp(c, f.opOptions...), true
+}
+
+// List 全てのゾーンのアプライアンスを並行して取得する。
+// 一部のゾーンで失敗した場合は取得できたアプライアンスと*FleetErrorを返す
+func (f *Fleet) List(ctx context.Context) ([]FleetAppliance, error) {
+	results := make([][]v1.GetNosqlAppliance, len(f.zones))
+	errs := make([]error, len(f.zones))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, f.concurrency)
+	for i, zone := range f.zones {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = NewError("Fleet.List", ctx.Err())
+				return
+			}
+			defer func() { <-sem }()
+
+			db, _ := f.Database(zone)
+			results[i], errs[i] = db.List(ctx)
+		}()
+	}
+	wg.Wait()
+
+	var ret []FleetAppliance
+	var zoneErrs []*ZoneError
+	for i, zone := range f.zones {
+		if errs[i] != nil {
+			zoneErrs = append(zoneErrs, &ZoneError{Zone: zone, Err: errs[i]})
+			continue
+		}
+		for _, appliance := range results[i] {
+			ret = append(ret, FleetAppliance{Zone: zone, Appliance: appliance})
+		}
+	}
+	if len(zoneErrs) > 0 {
+		return ret, &FleetError{Errors: zoneErrs}
+	}
+	return ret, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/sacloud/nosql-api-go"
+	"github.com/sacloud/saclient-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFleet_List(t *testing.T) {
+	assert := require.New(t)
+
+	var running, maxRunning atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/zone/is1a/appliance":
+			_, _ = w.Write([]byte(`{"From":0,"Count":2,"Total":2,"Appliances":[{"ID":"111"},{"ID":"112"}]}`))
+		case "/zone/tk1b/appliance":
+			_, _ = w.Write([]byte(`{"From":0,"Count":1,"Total":1,"Appliances":[{"ID":"222"}]}`))
+		case "/zone/tk1v/appliance":
+			_, _ = w.Write([]byte(`{"From":0,"Count":0,"Total":0,"Appliances":[]}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"is_fatal":true,"status":"401 Unauthorized","error_code":"unauthorized","error_msg":"認証に失敗しました。"}`))
+		}
+	}))
+	defer server.Close()
+
+	var theClient saclient.Client
+	assert.NoError(theClient.SetEnviron([]string{"SAKURA_RATE_LIMIT=1000", "SAKURA_ENDPOINTS_NOSQL=" + server.URL + "/zone/{zone}"}))
+	fleet, err := NewFleet(&theClient, []string{"tk1b", "is1a", "is1b", "tk1v"}, &FleetOptions{Concurrency: 2})
+	assert.NoError(err)
+
+	appliances, err := fleet.List(t.Context())
+	assert.Len(appliances, 3)
+	assert.Equal("is1a", appliances[0].Zone)
+	assert.Equal("111", appliances[0].Appliance.ID.Value)
+	assert.Equal("tk1b", appliances[2].Zone)
+	assert.Equal("222", appliances[2].Appliance.ID.Value)
+
+	var fleetErr *FleetError
+	assert.ErrorAs(err, &fleetErr)
+	assert.Len(fleetErr.Errors, 1)
+	assert.Equal("is1b", fleetErr.Errors[0].Zone)
+	assert.True(IsUnauthorized(err))
+	assert.LessOrEqual(maxRunning.Load(), int32(2))
+}
+
+func TestNewFleet_InvalidZone(t *testing.T) {
+	var theClient saclient.Client
+	_, err := NewFleet(&theClient, []string{"tk1b", "xx1a"}, nil)
+	require.Error(t, err)
+}
+
+func TestNewFleetWithClients_ZoneMismatch(t *testing.T) {
+	assert := require.New(t)
+
+	var theClient saclient.Client
+	is1b, err := NewClientForZone(&theClient, "is1b")
+	assert.NoError(err)
+
+	_, err = NewFleetWithClients(map[string]*Client{"is1b": is1b}, nil)
+	assert.NoError(err)
+	_, err = NewFleetWithClients(map[string]*Client{"tk1b": is1b}, nil)
+	assert.ErrorContains(err, `the client for zone "tk1b" connects to zone "is1b"`)
+}
@@ -5,7 +5,8 @@ package nosql
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"net/netip"
 
 	v1 "github.com/sacloud/nosql-api-go/apis/v1"
 )
@@ -17,260 +18,455 @@ type InstanceAPI interface {
 	SetParameters(ctx context.Context, params []v1.NosqlPutParameter) error
 	GetNodeHealth(ctx context.Context) (v1.NodeHealthNosqlStatus, error)
 	AddNodes(ctx context.Context, plan Plan, request v1.NosqlCreateRequestAppliance) (*v1.NosqlAppliance, error)
-	Recover(ctx context.Context) (string, error)
+	Recover(ctx context.Context) (RecoveryResult, error)
 	Repair(ctx context.Context, repairType string) error
 	Start(ctx context.Context) error
 	Stop(ctx context.Context) error
+	StartAndWait(ctx context.Context, opts *WaitOptions) (*v1.Instance, error)
+	StopAndWait(ctx context.Context, opts *WaitOptions) (*v1.Instance, error)
+	RecoverAndWait(ctx context.Context, opts *WaitOptions) (*RecoveryReport, error)
+	UpgradeAndWait(ctx context.Context, version string, opts *UpgradeOptions) (*UpgradeReport, error)
+}
+
+const (
+	// InstanceStatusUp 起動状態を示すInstance.Statusの値
+	InstanceStatusUp = "up"
+	// InstanceStatusDown 停止状態を示すInstance.Statusの値
+	InstanceStatusDown = "down"
+)
+
+// RecoveryResult Recoverの結果
+type RecoveryResult string
+
+const (
+	// RecoveryResultOK 復旧が完了した
+	RecoveryResultOK RecoveryResult = "ok"
+	// RecoveryResultInProgress 復旧が受け付けられ、実行中
+	RecoveryResultInProgress RecoveryResult = "in_progress"
+)
+
+func (RecoveryResult) AllValues() []RecoveryResult {
+	return []RecoveryResult{
+		RecoveryResultOK,
+		RecoveryResultInProgress,
+	}
+}
+
+// RecoveredNode RecoverAndWaitで置き換えられたデッドノード
+type RecoveredNode struct {
+	ApplianceID   string
+	Index         int
+	UserIPAddress netip.Addr
+}
+
+// RecoveryReport RecoverAndWaitの結果
+type RecoveryReport struct {
+	// Result Recoverの応答
+	Result RecoveryResult
+	// Health 待機完了時のノードの状態
+	Health v1.NodeHealthNosqlStatus
+	// ReplacedNodes Recover前にデッドノードだったノード
+	ReplacedNodes []RecoveredNode
 }
 
 var _ InstanceAPI = (*instanceOp)(nil)
 
 type instanceOp struct {
-	client *v1.Client
+	client *Client
 	dbId   string
 	zone   string
+	opConfig
 }
 
-func NewInstanceOp(client *v1.Client, dbId string, zone string) InstanceAPI {
-	return &instanceOp{client: client, dbId: dbId, zone: zone}
+// NewInstanceOp アプライアンスを操作するInstanceAPIを作成する。
+// zoneはAddNodesで使われ、空の場合はクライアントのゾーンを使う。クライアントのゾーンと異なる場合はAddNodesがエラーを返す
+func NewInstanceOp(client *Client, dbId string, zone string, opts ...OpOption) InstanceAPI {
+	return &instanceOp{client: client, dbId: dbId, zone: zone, opConfig: newOpConfig(opts)}
 }
 
 func (op *instanceOp) GetVersion(ctx context.Context) (*v1.NosqlGetVersionResponseNosql, error) {
-	res, err := op.client.GetVersion(ctx, v1.GetVersionParams{ApplianceID: op.dbId})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.GetVersionOperation, func(ctx context.Context) (v1.GetVersionRes, error) {
+		return op.client.GetVersion(ctx, v1.GetVersionParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return nil, NewAPIError("Instance.GetVersion", 0, err)
+		return nil, newAPIError(ctx, "Instance.GetVersion", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlGetVersionResponse:
 		return &p.Nosql.Value, nil
 	case *v1.BadRequestResponse:
-		return nil, NewAPIError("Instance.GetVersion", 400, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Instance.GetVersion", 400, p)
 	case *v1.UnauthorizedResponse:
-		return nil, NewAPIError("Instance.GetVersion", 401, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Instance.GetVersion", 401, p)
 	case *v1.ServerErrorResponse:
-		return nil, NewAPIError("Instance.GetVersion", 500, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Instance.GetVersion", 500, p)
 	default:
-		return nil, NewAPIError("Instance.GetVersion", 0, nil)
+		return nil, newAPIError(ctx, "Instance.GetVersion", 0, nil)
 	}
 }
 
 func (op *instanceOp) UpgradeVersion(ctx context.Context, version string) error {
-	res, err := op.client.PutVersion(ctx, &v1.NosqlPutVersionRequest{
-		Nosql: v1.NosqlVersion{Version: v1.NewOptString(version)}},
-		v1.PutVersionParams{ApplianceID: op.dbId})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.PutVersionOperation, func(ctx context.Context) (v1.PutVersionRes, error) {
+		return op.client.PutVersion(ctx, &v1.NosqlPutVersionRequest{
+			Nosql: v1.NosqlVersion{Version: v1.NewOptString(version)}},
+			v1.PutVersionParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return NewAPIError("Instance.UpgradeVersion", 0, err)
+		return newAPIError(ctx, "Instance.UpgradeVersion", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlPutVersionResponse:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Instance.UpgradeVersion", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.UpgradeVersion", 400, p)
 	case *v1.UnauthorizedResponse:
-		return NewAPIError("Instance.UpgradeVersion", 401, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.UpgradeVersion", 401, p)
 	case *v1.ConflictErrorResponse:
-		return NewAPIError("Instance.UpgradeVersion", 409, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.UpgradeVersion", 409, p)
 	case *v1.ServerErrorResponse:
-		return NewAPIError("Instance.UpgradeVersion", 500, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.UpgradeVersion", 500, p)
 	default:
-		return NewAPIError("Instance.UpgradeVersion", 0, nil)
+		return newAPIError(ctx, "Instance.UpgradeVersion", 0, nil)
 	}
 }
 
 func (op *instanceOp) GetParameters(ctx context.Context) ([]v1.NosqlGetParameter, error) {
-	res, err := op.client.GetParameter(ctx, v1.GetParameterParams{ApplianceID: op.dbId})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.GetParameterOperation, func(ctx context.Context) (v1.GetParameterRes, error) {
+		return op.client.GetParameter(ctx, v1.GetParameterParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return nil, NewAPIError("Instance.GetParameters", 0, err)
+		return nil, newAPIError(ctx, "Instance.GetParameters", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.GetParameterResponse:
 		return p.Nosql.Value.Parameters, nil
 	case *v1.BadRequestResponse:
-		return nil, NewAPIError("Instance.GetParameters", 400, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Instance.GetParameters", 400, p)
 	case *v1.UnauthorizedResponse:
-		return nil, NewAPIError("Instance.GetParameters", 401, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Instance.GetParameters", 401, p)
 	case *v1.ServerErrorResponse:
-		return nil, NewAPIError("Instance.GetParameters", 500, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Instance.GetParameters", 500, p)
 	default:
-		return nil, NewAPIError("Instance.GetParameters", 0, nil)
+		return nil, newAPIError(ctx, "Instance.GetParameters", 0, nil)
 	}
 }
 
 func (op *instanceOp) SetParameters(ctx context.Context, params []v1.NosqlPutParameter) error {
-	res, err := op.client.PutParameter(ctx, &v1.PutParameterRequest{
-		Nosql: v1.PutParameterRequestNosql{Parameters: params}},
-		v1.PutParameterParams{ApplianceID: op.dbId})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.PutParameterOperation, func(ctx context.Context) (v1.PutParameterRes, error) {
+		return op.client.PutParameter(ctx, &v1.PutParameterRequest{
+			Nosql: v1.PutParameterRequestNosql{Parameters: params}},
+			v1.PutParameterParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return NewAPIError("Instance.SetParameters", 0, err)
+		return newAPIError(ctx, "Instance.SetParameters", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.PutParameterResponse:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Instance.SetParameters", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.SetParameters", 400, p)
 	case *v1.UnauthorizedResponse:
-		return NewAPIError("Instance.SetParameters", 401, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.SetParameters", 401, p)
 	case *v1.ConflictErrorResponse:
-		return NewAPIError("Instance.SetParameters", 409, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.SetParameters", 409, p)
 	case *v1.ServerErrorResponse:
-		return NewAPIError("Instance.SetParameters", 500, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.SetParameters", 500, p)
 	default:
-		return NewAPIError("Instance.SetParameters", 0, nil)
+		return newAPIError(ctx, "Instance.SetParameters", 0, nil)
 	}
 }
 
 func (op *instanceOp) GetNodeHealth(ctx context.Context) (v1.NodeHealthNosqlStatus, error) {
-	res, err := op.client.GetNoSQLNodeHealth(ctx, v1.GetNoSQLNodeHealthParams{ApplianceID: op.dbId})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.GetNoSQLNodeHealthOperation, func(ctx context.Context) (v1.GetNoSQLNodeHealthRes, error) {
+		return op.client.GetNoSQLNodeHealth(ctx, v1.GetNoSQLNodeHealthParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return v1.NodeHealthNosqlStatus(""), NewAPIError("Instance.GetNodeHealth", 0, err)
+		return v1.NodeHealthNosqlStatus(""), newAPIError(ctx, "Instance.GetNodeHealth", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NodeHealth:
 		return p.Nosql.Value.Status.Value, nil
 	case *v1.BadRequestResponse:
-		return v1.NodeHealthNosqlStatus(""), NewAPIError("Instance.GetNodeHealth", 400, errors.New(p.ErrorMsg.Value))
+		return v1.NodeHealthNosqlStatus(""), newAPIResponseError(ctx, "Instance.GetNodeHealth", 400, p)
 	case *v1.UnauthorizedResponse:
-		return v1.NodeHealthNosqlStatus(""), NewAPIError("Instance.GetNodeHealth", 401, errors.New(p.ErrorMsg.Value))
+		return v1.NodeHealthNosqlStatus(""), newAPIResponseError(ctx, "Instance.GetNodeHealth", 401, p)
 	case *v1.NotFoundResponse:
-		return v1.NodeHealthNosqlStatus(""), NewAPIError("Instance.GetNodeHealth", 404, errors.New(p.ErrorMsg.Value))
+		return v1.NodeHealthNosqlStatus(""), newAPIResponseError(ctx, "Instance.GetNodeHealth", 404, p)
 	case *v1.ServerErrorResponse:
-		return v1.NodeHealthNosqlStatus(""), NewAPIError("Instance.GetNodeHealth", 500, errors.New(p.ErrorMsg.Value))
+		return v1.NodeHealthNosqlStatus(""), newAPIResponseError(ctx, "Instance.GetNodeHealth", 500, p)
 	default:
-		return v1.NodeHealthNosqlStatus(""), NewAPIError("Instance.GetNodeHealth", 0, nil)
+		return v1.NodeHealthNosqlStatus(""), newAPIError(ctx, "Instance.GetNodeHealth", 0, nil)
 	}
 }
 
 func (op *instanceOp) AddNodes(ctx context.Context, plan Plan, request v1.NosqlCreateRequestAppliance) (*v1.NosqlAppliance, error) {
-	if op.zone == "" {
-		return nil, NewError("Instance.AddNodes", errors.New("zone must be specified via NewInstanceOpWithZone"))
+	zone, err := resolveZone(op.client, op.zone)
+	if err != nil {
+		return nil, NewError("Instance.AddNodes", err)
+	}
+	if plan.GetMaxNodeGroups() == 0 {
+		return nil, NewError("Instance.AddNodes", &NodeLimitError{Plan: plan, NodeGroups: 1})
+	}
+	status, err := NewDatabaseOp(op.client, op.options()...).GetStatus(ctx, op.dbId)
+	if err != nil {
+		return nil, err
+	}
+	if groups := len(status.AddNodes) + 1; groups > plan.GetMaxNodeGroups() {
+		return nil, NewError("Instance.AddNodes", &NodeLimitError{Plan: plan, NodeGroups: groups, MaxNodeGroups: plan.GetMaxNodeGroups()})
 	}
 
 	request.Class = "nosql"
 	request.Plan = v1.Plan{ID: plan.GetPlanIDforNodes()}
 	request.ServiceClass = plan.GetServiceClassForNodes()
 	request.Remark.Nosql.PrimaryNodes = v1.NewOptNosqlRemarkNosqlPrimaryNodes(v1.NosqlRemarkNosqlPrimaryNodes{
-		Appliance: v1.NosqlRemarkNosqlPrimaryNodesAppliance{ID: op.dbId, Zone: v1.NosqlRemarkNosqlPrimaryNodesApplianceZone{Name: op.zone}},
+		Appliance: v1.NosqlRemarkNosqlPrimaryNodesAppliance{ID: op.dbId, Zone: v1.NosqlRemarkNosqlPrimaryNodesApplianceZone{Name: zone}},
+	})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.CreateDBOperation, func(ctx context.Context) (v1.CreateDBRes, error) {
+		return op.client.CreateDB(ctx, &v1.NosqlCreateRequest{Appliance: request})
 	})
-	res, err := op.client.CreateDB(ctx, &v1.NosqlCreateRequest{Appliance: request})
 	if err != nil {
-		return nil, NewAPIError("Instance.AddNodes", 0, err)
+		return nil, newAPIError(ctx, "Instance.AddNodes", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlCreateResponse:
 		return &p.Appliance, nil
 	case *v1.BadRequestResponse:
-		return nil, NewAPIError("Instance.AddNodes", 400, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Instance.AddNodes", 400, p)
 	case *v1.UnauthorizedResponse:
-		return nil, NewAPIError("Instance.AddNodes", 401, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Instance.AddNodes", 401, p)
 	case *v1.ConflictErrorResponse:
-		return nil, NewAPIError("Instance.AddNodes", 409, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Instance.AddNodes", 409, p)
 	case *v1.ServerErrorResponse:
-		return nil, NewAPIError("Instance.AddNodes", 500, errors.New(p.ErrorMsg.Value))
+		return nil, newAPIResponseError(ctx, "Instance.AddNodes", 500, p)
 	default:
-		return nil, NewAPIError("Instance.AddNodes", 0, nil)
+		return nil, newAPIError(ctx, "Instance.AddNodes", 0, nil)
 	}
 }
 
-func (op *instanceOp) Recover(ctx context.Context) (string, error) {
-	res, err := op.client.RecoverNoSQLNode(ctx, v1.RecoverNoSQLNodeParams{ApplianceID: op.dbId})
+func (op *instanceOp) Recover(ctx context.Context) (RecoveryResult, error) {
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.RecoverNoSQLNodeOperation, func(ctx context.Context) (v1.RecoverNoSQLNodeRes, error) {
+		return op.client.RecoverNoSQLNode(ctx, v1.RecoverNoSQLNodeParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return "", NewAPIError("Instance.Recover", 0, err)
+		return "", newAPIError(ctx, "Instance.Recover", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.RecoverNoSQLNodeOK:
-		return "ok", nil
+		return RecoveryResultOK, nil
 	case *v1.RecoverNoSQLNodeAccepted:
-		return "in_progress", nil
+		return RecoveryResultInProgress, nil
 	case *v1.BadRequestResponse:
-		return "", NewAPIError("Instance.Recover", 400, errors.New(p.ErrorMsg.Value))
+		return "", newAPIResponseError(ctx, "Instance.Recover", 400, p)
 	case *v1.UnauthorizedResponse:
-		return "", NewAPIError("Instance.Recover", 401, errors.New(p.ErrorMsg.Value))
+		return "", newAPIResponseError(ctx, "Instance.Recover", 401, p)
 	case *v1.NotFoundResponse:
-		return "", NewAPIError("Instance.Recover", 404, errors.New(p.ErrorMsg.Value))
+		return "", newAPIResponseError(ctx, "Instance.Recover", 404, p)
 	case *v1.ServerErrorResponse:
-		return "", NewAPIError("Instance.Recover", 500, errors.New(p.ErrorMsg.Value))
+		return "", newAPIResponseError(ctx, "Instance.Recover", 500, p)
 	default:
-		return "", NewAPIError("Instance.Recover", 0, nil)
+		return "", newAPIError(ctx, "Instance.Recover", 0, nil)
 	}
 }
 
 func (op *instanceOp) Repair(ctx context.Context, repairType string) error {
-	res, err := op.client.PostNoSQLRepair(ctx, &v1.NosqlRepairRequest{
-		Nosql: v1.NewOptNosqlRepairRequestNosql(v1.NosqlRepairRequestNosql{RepairType: v1.NewOptNosqlRepairRequestNosqlRepairType(v1.NosqlRepairRequestNosqlRepairType(repairType))})},
-		v1.PostNoSQLRepairParams{ApplianceID: op.dbId})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.PostNoSQLRepairOperation, func(ctx context.Context) (v1.PostNoSQLRepairRes, error) {
+		return op.client.PostNoSQLRepair(ctx, &v1.NosqlRepairRequest{
+			Nosql: v1.NewOptNosqlRepairRequestNosql(v1.NosqlRepairRequestNosql{RepairType: v1.NewOptNosqlRepairRequestNosqlRepairType(v1.NosqlRepairRequestNosqlRepairType(repairType))})},
+			v1.PostNoSQLRepairParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return NewAPIError("Instance.Repair", 0, err)
+		return newAPIError(ctx, "Instance.Repair", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.NosqlRepairRequest:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Instance.Repair", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Repair", 400, p)
 	case *v1.UnauthorizedResponse:
-		return NewAPIError("Instance.Repair", 401, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Repair", 401, p)
 	case *v1.NotFoundResponse:
-		return NewAPIError("Instance.Repair", 404, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Repair", 404, p)
 	case *v1.ConflictErrorResponse:
-		return NewAPIError("Instance.Repair", 409, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Repair", 409, p)
 	case *v1.ServerErrorResponse:
-		return NewAPIError("Instance.Repair", 500, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Repair", 500, p)
 	default:
-		return NewAPIError("Instance.Repair", 0, nil)
+		return newAPIError(ctx, "Instance.Repair", 0, nil)
 	}
 }
 
 func (op *instanceOp) Start(ctx context.Context) error {
-	res, err := op.client.PutAppliancePower(ctx, v1.PutAppliancePowerParams{ApplianceID: op.dbId})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.PutAppliancePowerOperation, func(ctx context.Context) (v1.PutAppliancePowerRes, error) {
+		return op.client.PutAppliancePower(ctx, v1.PutAppliancePowerParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return NewAPIError("Instance.Start", 0, err)
+		return newAPIError(ctx, "Instance.Start", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.SuccessResponse:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Instance.Start", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Start", 400, p)
 	case *v1.UnauthorizedResponse:
-		return NewAPIError("Instance.Start", 401, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Start", 401, p)
 	case *v1.NotFoundResponse:
-		return NewAPIError("Instance.Start", 404, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Start", 404, p)
 	case *v1.ConflictErrorResponse:
-		return NewAPIError("Instance.Start", 409, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Start", 409, p)
 	case *v1.ServerErrorResponse:
-		return NewAPIError("Instance.Start", 500, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Start", 500, p)
 	default:
-		return NewAPIError("Instance.Start", 0, nil)
+		return newAPIError(ctx, "Instance.Start", 0, nil)
 	}
 }
 
 func (op *instanceOp) Stop(ctx context.Context) error {
-	res, err := op.client.DeleteAppliancePower(ctx, v1.DeleteAppliancePowerParams{ApplianceID: op.dbId})
+	ctx = withDiagnostics(ctx)
+	res, err := callWithRetry(ctx, op.retry, v1.DeleteAppliancePowerOperation, func(ctx context.Context) (v1.DeleteAppliancePowerRes, error) {
+		return op.client.DeleteAppliancePower(ctx, v1.DeleteAppliancePowerParams{ApplianceID: op.dbId})
+	})
 	if err != nil {
-		return NewAPIError("Instance.Stop", 0, err)
+		return newAPIError(ctx, "Instance.Stop", 0, err)
 	}
 
 	switch p := res.(type) {
 	case *v1.SuccessResponse:
 		return nil
 	case *v1.BadRequestResponse:
-		return NewAPIError("Instance.Stop", 400, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Stop", 400, p)
 	case *v1.UnauthorizedResponse:
-		return NewAPIError("Instance.Stop", 401, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Stop", 401, p)
 	case *v1.NotFoundResponse:
-		return NewAPIError("Instance.Stop", 404, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Stop", 404, p)
 	case *v1.ConflictErrorResponse:
-		return NewAPIError("Instance.Stop", 409, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Stop", 409, p)
 	case *v1.ServerErrorResponse:
-		return NewAPIError("Instance.Stop", 500, errors.New(p.ErrorMsg.Value))
+		return newAPIResponseError(ctx, "Instance.Stop", 500, p)
 	default:
-		return NewAPIError("Instance.Stop", 0, nil)
+		return newAPIError(ctx, "Instance.Stop", 0, nil)
+	}
+}
+
+// StartAndWait 起動してInstance.Statusがupになるまで待機する
+func (op *instanceOp) StartAndWait(ctx context.Context, opts *WaitOptions) (*v1.Instance, error) {
+	return op.changePowerAndWait(ctx, "Instance.StartAndWait", InstanceStatusUp, op.Start, opts)
+}
+
+// StopAndWait 停止してInstance.Statusがdownになるまで待機する
+func (op *instanceOp) StopAndWait(ctx context.Context, opts *WaitOptions) (*v1.Instance, error) {
+	return op.changePowerAndWait(ctx, "Instance.StopAndWait", InstanceStatusDown, op.Stop, opts)
+}
+
+func (op *instanceOp) changePowerAndWait(ctx context.Context, name string, target string, change func(context.Context) error, opts *WaitOptions) (_ *v1.Instance, err error) {
+	ctx, span := startSpan(ctx, op.client, name, op.dbId)
+	defer func() { endSpan(span, err) }()
+
+	if err := change(ctx); err != nil {
+		return nil, err
+	}
+
+	// 目的の状態に達していれば完了とする。StatusChangedAtは省略されることがあるため判定に使わない
+	var instance *v1.Instance
+	err = poll(ctx, metricsOf(op.client), name, opts, func(ctx context.Context) (string, bool, error) {
+		res, err := op.readInstance(ctx)
+		if err != nil {
+			return "", false, err
+		}
+		instance = res
+
+		status := res.Status.Value
+		return status, status == target, nil
+	})
+	return instance, err
+}
+
+func (op *instanceOp) readInstance(ctx context.Context) (*v1.Instance, error) {
+	appliance, err := NewDatabaseOp(op.client, op.options()...).Read(ctx, op.dbId)
+	if err != nil {
+		return nil, err
+	}
+	return &appliance.Instance.Value, nil
+}
+
+// RecoverAndWait Recoverを実行し、ノードの状態がhealthyになりデッドノードがなくなるまで待機する
+func (op *instanceOp) RecoverAndWait(ctx context.Context, opts *WaitOptions) (_ *RecoveryReport, err error) {
+	ctx, span := startSpan(ctx, op.client, "Instance.RecoverAndWait", op.dbId)
+	defer func() { endSpan(span, err) }()
+
+	databaseOp := NewDatabaseOp(op.client, op.options()...)
+	before, err := databaseOp.GetStatus(ctx, op.dbId)
+	if err != nil {
+		return nil, err
+	}
+	dead := deadNodes(before)
+
+	result, err := op.Recover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RecoveryReport{Result: result, ReplacedNodes: dead}
+	err = poll(ctx, metricsOf(op.client), "Instance.RecoverAndWait", opts, func(ctx context.Context) (string, bool, error) {
+		health, err := op.GetNodeHealth(ctx)
+		if err != nil {
+			return "", false, err
+		}
+		report.Health = health
+		if health != v1.NodeHealthNosqlStatusHealthy {
+			return string(health), false, nil
+		}
+
+		status, err := databaseOp.GetStatus(ctx, op.dbId)
+		if err != nil {
+			return "", false, err
+		}
+		if remaining := deadNodes(status); len(remaining) > 0 {
+			return fmt.Sprintf("%s (%d dead nodes)", health, len(remaining)), false, nil
+		}
+		return string(health), true, nil
+	})
+	return report, err
+}
+
+// deadNodes ステータスに含まれるNodeTypeが1(デッドノード)のノードを返す
+func deadNodes(status *v1.NosqlStatusResponseApplianceSettingsResponseNosql) []RecoveredNode {
+	var appliances []v1.NosqlNodeAppliance
+	if primary, ok := status.PrimaryNodes.Value.Appliance.Get(); ok {
+		appliances = append(appliances, primary)
+	}
+	for _, added := range status.AddNodes {
+		appliances = append(appliances, added.Appliance)
+	}
+
+	var ret []RecoveredNode
+	for _, appliance := range appliances {
+		for _, node := range appliance.Nodes {
+			if node.NodeType.Value == v1.NosqldbNodeStatusNodeType1 {
+				ret = append(ret, RecoveredNode{
+					ApplianceID:   appliance.ID,
+					Index:         node.Index.Value,
+					UserIPAddress: node.UserIPAddress.Value,
+				})
+			}
+		}
 	}
+	return ret
 }
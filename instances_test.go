@@ -0,0 +1,111 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceOp_StartAndWait(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("PUT /appliance/123/power", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Success":true,"is_ok":true}`
+	})
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		switch n {
+		case 1, 2:
+			return http.StatusOK, `{"Appliance":{"ID":"123","Instance":{"Status":"down","StatusChangedAt":"2025-01-01T00:00:00+09:00"}}}`
+		default:
+			return http.StatusOK, `{"Appliance":{"ID":"123","Instance":{"Status":"up","StatusChangedAt":"2025-01-01T00:05:00+09:00"}}}`
+		}
+	})
+
+	instance, err := NewInstanceOp(api.Client(), "123", "tk1b").StartAndWait(t.Context(), fastWait)
+	assert.NoError(err)
+	assert.Equal(InstanceStatusUp, instance.Status.Value)
+	assert.Equal(1, api.Calls("PUT /appliance/123/power"))
+	assert.Equal(3, api.Calls("GET /appliance/123"))
+}
+
+func TestInstanceOp_StartAndWait_WithoutStatusChangedAt(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("PUT /appliance/123/power", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Success":true,"is_ok":true}`
+	})
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		if n == 1 {
+			return http.StatusOK, `{"Appliance":{"ID":"123","Instance":{"Status":"down"}}}`
+		}
+		return http.StatusOK, `{"Appliance":{"ID":"123","Instance":{"Status":"up"}}}`
+	})
+
+	instance, err := NewInstanceOp(api.Client(), "123", "tk1b").StartAndWait(t.Context(), fastWait)
+	assert.NoError(err)
+	assert.Equal(InstanceStatusUp, instance.Status.Value)
+	assert.Equal(2, api.Calls("GET /appliance/123"))
+}
+
+func TestInstanceOp_StopAndWait_Timeout(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("DELETE /appliance/123/power", func(int, *http.Request) (int, string) {
+		return http.StatusAccepted, `{"Success":true,"is_ok":true}`
+	})
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","Instance":{"Status":"up","StatusChangedAt":"2025-01-01T00:00:00+09:00"}}}`
+	})
+
+	instance, err := NewInstanceOp(api.Client(), "123", "tk1b").StopAndWait(t.Context(),
+		&WaitOptions{Interval: time.Millisecond, Timeout: 100 * time.Millisecond})
+	assert.ErrorIs(err, ErrWaitTimeout)
+	assert.NotNil(instance)
+	assert.Equal(InstanceStatusUp, instance.Status.Value)
+}
+
+func TestInstanceOp_RecoverAndWait(t *testing.T) {
+	assert := require.New(t)
+
+	const (
+		degraded  = `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"PrimaryNodes":{"Appliance":{"ID":"123","Availability":"available","Nodes":[{"Index":0,"UserIPAddress":"192.168.0.4","NodeType":"0"},{"Index":1,"UserIPAddress":"192.168.0.5","NodeType":"1"}]}}}}}}`
+		recovered = `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"PrimaryNodes":{"Appliance":{"ID":"123","Availability":"available","Nodes":[{"Index":0,"UserIPAddress":"192.168.0.4","NodeType":"0"},{"Index":1,"UserIPAddress":"192.168.0.10","NodeType":"0"}]}}}}}}`
+	)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/status", func(n int, _ *http.Request) (int, string) {
+		if n <= 2 {
+			return http.StatusOK, degraded
+		}
+		return http.StatusOK, recovered
+	})
+	api.Handle("POST /appliance/123/nosql/nodes/recover", func(int, *http.Request) (int, string) {
+		return http.StatusAccepted, `{"Success":true,"is_ok":true}`
+	})
+	api.Handle("GET /appliance/123/nosql/nodes/health", func(n int, _ *http.Request) (int, string) {
+		if n == 1 {
+			return http.StatusOK, `{"Nosql":{"Status":"healthy-partial"}}`
+		}
+		return http.StatusOK, `{"Nosql":{"Status":"healthy"}}`
+	})
+
+	report, err := NewInstanceOp(api.Client(), "123", "tk1b").RecoverAndWait(t.Context(), fastWait)
+	assert.NoError(err)
+	assert.Equal(RecoveryResultInProgress, report.Result)
+	assert.Equal(v1.NodeHealthNosqlStatusHealthy, report.Health)
+	assert.Equal([]RecoveredNode{
+		{ApplianceID: "123", Index: 1, UserIPAddress: netip.MustParseAddr("192.168.0.5")},
+	}, report.ReplacedNodes)
+	assert.Equal(3, api.Calls("GET /appliance/123/status"))
+}
@@ -0,0 +1,126 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// gen-routes openapi.jsonのパスとメソッドからオペレーション名の対応表(operation_routes_gen.go)を生成する
+//
+//	go run ./internal/tools/gen-routes <openapi.json> <output>
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// methods openapi.jsonのパスアイテムのうちオペレーションを表すキー
+var methods = []string{
+	http.MethodGet,
+	http.MethodPut,
+	http.MethodPost,
+	http.MethodDelete,
+	http.MethodOptions,
+	http.MethodHead,
+	http.MethodPatch,
+	http.MethodTrace,
+}
+
+type route struct {
+	method   string
+	path     string
+	constant string
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatal("usage: gen-routes <openapi.json> <output>")
+	}
+	routes, err := loadRoutes(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := render(routes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(os.Args[2], src, 0o644); err != nil { //nolint:gosec
+		log.Fatal(err)
+	}
+}
+
+func loadRoutes(spec string) ([]route, error) {
+	data, err := os.ReadFile(spec) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", spec, err)
+	}
+
+	var routes []route
+	for path, item := range doc.Paths {
+		for _, method := range methods {
+			raw, ok := item[strings.ToLower(method)]
+			if !ok {
+				continue
+			}
+			var op struct {
+				OperationID string `json:"operationId"`
+			}
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("unable to parse %s %s: %w", method, path, err)
+			}
+			if op.OperationID == "" {
+				return nil, fmt.Errorf("%s %s has no operationId", method, path)
+			}
+			routes = append(routes, route{method: method, path: path, constant: operationConstant(op.OperationID)})
+		}
+	}
+	slices.SortFunc(routes, func(a, b route) int {
+		if c := strings.Compare(a.path, b.path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.method, b.method)
+	})
+	return routes, nil
+}
+
+// operationConstant ogenが生成するv1.OperationNameの定数名を返す
+func operationConstant(operationID string) string {
+	return strings.ToUpper(operationID[:1]) + operationID[1:] + "Operation"
+}
+
+func render(routes []route) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`// Code generated by gen-routes from openapi/openapi.json, DO NOT EDIT.
+
+package nosql
+
+import (
+	"net/http"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// operationRouteTemplates openapi.jsonのパスとメソッドに対応するオペレーション名
+var operationRouteTemplates = []operationRouteTemplate{
+`)
+	for _, r := range routes {
+		fmt.Fprintf(&buf, "\t{http.Method%s, %q, v1.%s},\n", methodConstant(r.method), r.path, r.constant)
+	}
+	buf.WriteString("}\n")
+	return format.Source(buf.Bytes())
+}
+
+// methodConstant http.MethodGetなどの定数名の接尾辞を返す
+func methodConstant(method string) string {
+	return method[:1] + strings.ToLower(method[1:])
+}
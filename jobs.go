@@ -0,0 +1,287 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// JobType ConfirmStatusDBが返すジョブの種別。
+// 定数にない値もそのまま保持されるため、IsKnownで判別する。
+// openapi.jsonに記載があるのはJobTypeCreate(JobTypeのexample)のみで、その他はAPIの操作名から推定した値
+type JobType string
+
+const (
+	JobTypeCreate         JobType = "Create"         // openapi.json: JobTypeのexample
+	JobTypeDelete         JobType = "Delete"         // 推定(DeleteDB)
+	JobTypeUpdateConfig   JobType = "UpdateConfig"   // 推定(UpdateConfigDB)
+	JobTypeUpgradeVersion JobType = "UpgradeVersion" // 推定(PutVersion)
+	JobTypeAddNodes       JobType = "AddNodes"       // 推定(CreateDBのAddNodes)
+	JobTypeBackup         JobType = "Backup"         // 推定(CreateBackup)
+	JobTypeRestore        JobType = "Restore"        // 推定(RestoreBackup)
+	JobTypeRepair         JobType = "Repair"         // 推定(PostNoSQLRepair)
+	JobTypeRecover        JobType = "Recover"        // 推定(RecoverNoSQLNode)
+)
+
+func (JobType) AllValues() []JobType {
+	return []JobType{
+		JobTypeCreate,
+		JobTypeDelete,
+		JobTypeUpdateConfig,
+		JobTypeUpgradeVersion,
+		JobTypeAddNodes,
+		JobTypeBackup,
+		JobTypeRestore,
+		JobTypeRepair,
+		JobTypeRecover,
+	}
+}
+
+func (t JobType) IsKnown() bool {
+	return slices.Contains(t.AllValues(), t)
+}
+
+// JobStatus ConfirmStatusDBが返すジョブのステータス。
+// openapi.jsonに記載があるのはJobStatusDone(JobStatusのexample)のみで、その他は推定した値。
+// 定数にない値もそのまま保持され、実行中のものとして扱われる(待機はWaitOptionsのタイムアウトで打ち切られる)
+type JobStatus string
+
+const (
+	JobStatusPending JobStatus = "Pending" // 推定
+	JobStatusRunning JobStatus = "Running" // 推定
+	JobStatusDone    JobStatus = "Done"    // openapi.json: JobStatusのexample
+	JobStatusFailed  JobStatus = "Failed"  // 推定
+)
+
+func (JobStatus) AllValues() []JobStatus {
+	return []JobStatus{
+		JobStatusPending,
+		JobStatusRunning,
+		JobStatusDone,
+		JobStatusFailed,
+	}
+}
+
+func (s JobStatus) IsKnown() bool {
+	return slices.Contains(s.AllValues(), s)
+}
+
+// IsTerminal ジョブが終了しているか。DoneとFailed以外は未知の値も含めて終了していないとみなす
+func (s JobStatus) IsTerminal() bool {
+	return s == JobStatusDone || s == JobStatusFailed
+}
+
+// IsFailed ジョブが失敗したか。Failedのみを失敗とみなす
+func (s JobStatus) IsFailed() bool {
+	return s == JobStatusFailed
+}
+
+// Job ConfirmStatusDBが返すジョブ
+type Job struct {
+	Type   JobType
+	Status JobStatus
+}
+
+func (j Job) String() string {
+	return string(j.Type) + ":" + string(j.Status)
+}
+
+// JobsFromStatus GetStatusの結果からジョブの一覧を取り出す
+func JobsFromStatus(status *v1.NosqlStatusResponseApplianceSettingsResponseNosql) []Job {
+	if status == nil {
+		return nil
+	}
+	jobs := make([]Job, 0, len(status.Jobs))
+	for _, j := range status.Jobs {
+		jobs = append(jobs, Job{Type: JobType(j.JobType.Value), Status: JobStatus(j.JobStatus.Value)})
+	}
+	return jobs
+}
+
+// JobFilter WaitForJobsで待機対象とするジョブを選ぶ関数。nilの場合は全てのジョブが対象
+type JobFilter func(Job) bool
+
+// JobTypeIs 指定した種別のジョブを選ぶJobFilterを返す
+func JobTypeIs(types ...JobType) JobFilter {
+	return func(j Job) bool {
+		return slices.Contains(types, j.Type)
+	}
+}
+
+type JobEventKind string
+
+const (
+	JobStarted  JobEventKind = "started"
+	JobFinished JobEventKind = "finished"
+	JobFailed   JobEventKind = "failed"
+)
+
+// JobEvent 連続したステータスの差分から検出したジョブの状態変化
+type JobEvent struct {
+	ApplianceID string
+	Kind        JobEventKind
+	Job         Job
+}
+
+// JobFailedError 待機していたジョブが失敗したことを示すエラー
+type JobFailedError struct {
+	ApplianceID string
+	Job         Job
+}
+
+func (e *JobFailedError) Error() string {
+	return fmt.Sprintf("job %s on appliance %s failed (status: %s)", e.Job.Type, e.ApplianceID, e.Job.Status)
+}
+
+// JobTracker GetStatusのスナップショットを比較してジョブの開始・終了を追跡する
+type JobTracker struct {
+	client  *Client
+	api     DatabaseAPI
+	opts    *WaitOptions
+	onEvent func(JobEvent)
+
+	mu        sync.Mutex
+	snapshots map[string]map[jobKey]JobStatus
+}
+
+// jobKey ジョブにはIDがないため、種別とスナップショット内での出現順で同一性を判断する
+type jobKey struct {
+	jobType JobType
+	nth     int
+}
+
+func jobKeys(jobs []Job) []jobKey {
+	keys := make([]jobKey, len(jobs))
+	counts := map[JobType]int{}
+	for i, j := range jobs {
+		keys[i] = jobKey{jobType: j.Type, nth: counts[j.Type]}
+		counts[j.Type]++
+	}
+	return keys
+}
+
+// NewJobTracker clientのアプライアンスのジョブを追跡するJobTrackerを作成する。onEventはnilでもよい。
+// WaitForJobsはopOptsを指定したDatabaseAPIでステータスを取得する
+func NewJobTracker(client *Client, opts *WaitOptions, onEvent func(JobEvent), opOpts ...OpOption) *JobTracker {
+	return &JobTracker{
+		client:    client,
+		api:       NewDatabaseOp(client, opOpts...),
+		opts:      opts,
+		onEvent:   onEvent,
+		snapshots: map[string]map[jobKey]JobStatus{},
+	}
+}
+
+// Observe アプライアンスの新しいスナップショットを記録し、前回からの状態変化を返す。
+// 最初のスナップショットで既に終了しているジョブはイベントにしない
+func (t *JobTracker) Observe(id string, jobs []Job) []JobEvent {
+	t.mu.Lock()
+	prev, seen := t.snapshots[id]
+	next := make(map[jobKey]JobStatus, len(jobs))
+	var events []JobEvent
+	keys := jobKeys(jobs)
+	for i, j := range jobs {
+		key := keys[i]
+		next[key] = j.Status
+
+		before, existed := prev[key]
+		if existed && before == j.Status {
+			continue
+		}
+		switch {
+		case !j.Status.IsTerminal():
+			if !existed || before.IsTerminal() {
+				events = append(events, JobEvent{ApplianceID: id, Kind: JobStarted, Job: j})
+			}
+		case !seen:
+			// 追跡開始前に終了したジョブ
+		case !j.Status.IsFailed():
+			events = append(events, JobEvent{ApplianceID: id, Kind: JobFinished, Job: j})
+		default:
+			events = append(events, JobEvent{ApplianceID: id, Kind: JobFailed, Job: j})
+		}
+	}
+	t.snapshots[id] = next
+	t.mu.Unlock()
+
+	if t.onEvent != nil {
+		for _, e := range events {
+			t.onEvent(e)
+		}
+	}
+	return events
+}
+
+// WaitForJobs filterに合致するジョブが1つ以上現れ、その全てが終了するまで待機する。
+// ジョブの一覧は履歴を含むため、待機開始時点(既にObserveしている場合はその時点)で終了していたジョブは、
+// その後ステータスが変わらない限り対象にしない。操作の実行前にObserveしておくと、すぐに終わるジョブも取りこぼさない。
+// 合致したジョブを返し、失敗したジョブがあれば*JobFailedErrorを返す
+func (t *JobTracker) WaitForJobs(ctx context.Context, id string, filter JobFilter) (_ []Job, err error) {
+	ctx, span := startSpan(ctx, t.client, "Job.WaitForJobs", id)
+	defer func() { endSpan(span, err) }()
+
+	t.mu.Lock()
+	baseline, seen := t.snapshots[id]
+	t.mu.Unlock()
+	changed := map[jobKey]bool{}
+
+	var matched []Job
+	err = poll(ctx, metricsOf(t.client), "Job.WaitForJobs", t.opts, func(ctx context.Context) (string, bool, error) {
+		status, err := t.api.GetStatus(ctx, id)
+		if err != nil {
+			return "", false, err
+		}
+		jobs := JobsFromStatus(status)
+		t.Observe(id, jobs)
+
+		keys := jobKeys(jobs)
+		if !seen {
+			baseline = make(map[jobKey]JobStatus, len(jobs))
+			for i, j := range jobs {
+				baseline[keys[i]] = j.Status
+			}
+			seen = true
+		}
+
+		matched = matched[:0]
+		done := true
+		for i, j := range jobs {
+			if filter != nil && !filter(j) {
+				continue
+			}
+			key := keys[i]
+			if before, ok := baseline[key]; ok && before.IsTerminal() && before == j.Status && !changed[key] {
+				continue
+			}
+			changed[key] = true
+			matched = append(matched, j)
+			done = done && j.Status.IsTerminal()
+		}
+		return jobsState(matched), done && len(matched) > 0, nil
+	})
+	if err != nil {
+		return matched, err
+	}
+
+	for _, j := range matched {
+		if j.Status.IsFailed() {
+			return matched, NewError("Job.WaitForJobs", &JobFailedError{ApplianceID: id, Job: j})
+		}
+	}
+	return matched, nil
+}
+
+func jobsState(jobs []Job) string {
+	s := make([]string, 0, len(jobs))
+	for _, j := range jobs {
+		s = append(s, j.String())
+	}
+	return strings.Join(s, ",")
+}
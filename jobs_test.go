@@ -0,0 +1,126 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/sacloud/nosql-api-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobStatus(t *testing.T) {
+	assert := require.New(t)
+
+	assert.True(JobStatusDone.IsTerminal())
+	assert.True(JobStatusFailed.IsTerminal())
+	assert.False(JobStatusRunning.IsTerminal())
+	assert.False(JobStatusDone.IsFailed())
+	assert.True(JobStatusFailed.IsFailed())
+	assert.False(JobStatus("Error").IsTerminal())
+	assert.False(JobStatus("Error").IsFailed())
+	assert.False(JobStatus("Error").IsKnown())
+	assert.True(JobTypeCreate.IsKnown())
+	assert.False(JobType("Migrate").IsKnown())
+}
+
+func TestJobTracker_Observe(t *testing.T) {
+	assert := require.New(t)
+
+	var received []JobEvent
+	tracker := NewJobTracker(nil, nil, func(e JobEvent) { received = append(received, e) })
+
+	events := tracker.Observe("123", []Job{
+		{Type: JobTypeCreate, Status: JobStatusDone},
+		{Type: JobTypeUpdateConfig, Status: JobStatusRunning},
+	})
+	assert.Equal([]JobEvent{
+		{ApplianceID: "123", Kind: JobStarted, Job: Job{Type: JobTypeUpdateConfig, Status: JobStatusRunning}},
+	}, events)
+
+	events = tracker.Observe("123", []Job{
+		{Type: JobTypeCreate, Status: JobStatusDone},
+		{Type: JobTypeUpdateConfig, Status: JobStatusDone},
+		{Type: JobTypeBackup, Status: JobStatusFailed},
+	})
+	assert.Equal([]JobEvent{
+		{ApplianceID: "123", Kind: JobFinished, Job: Job{Type: JobTypeUpdateConfig, Status: JobStatusDone}},
+		{ApplianceID: "123", Kind: JobFailed, Job: Job{Type: JobTypeBackup, Status: JobStatusFailed}},
+	}, events)
+
+	assert.Len(received, 3)
+}
+
+func TestJobTracker_WaitForJobs(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/status", func(n int, _ *http.Request) (int, string) {
+		switch n {
+		case 1:
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[{"JobType":"Create","JobStatus":"Done"}]}}}}`
+		case 2:
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[{"JobType":"Create","JobStatus":"Done"},{"JobType":"UpgradeVersion","JobStatus":"Running"}]}}}}`
+		default:
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[{"JobType":"Create","JobStatus":"Done"},{"JobType":"UpgradeVersion","JobStatus":"Failed"}]}}}}`
+		}
+	})
+
+	var kinds []JobEventKind
+	tracker := NewJobTracker(api.Client(), fastWait, func(e JobEvent) { kinds = append(kinds, e.Kind) })
+
+	jobs, err := tracker.WaitForJobs(t.Context(), "123", JobTypeIs(JobTypeUpgradeVersion))
+	var failed *JobFailedError
+	assert.ErrorAs(err, &failed)
+	assert.Equal(JobTypeUpgradeVersion, failed.Job.Type)
+	assert.Equal([]Job{{Type: JobTypeUpgradeVersion, Status: JobStatusFailed}}, jobs)
+	assert.Equal([]JobEventKind{JobStarted, JobFailed}, kinds)
+}
+
+func TestJobTracker_WaitForJobs_IgnoresHistory(t *testing.T) {
+	assert := require.New(t)
+
+	const old = `{"JobType":"UpgradeVersion","JobStatus":"Done"}`
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/status", func(n int, _ *http.Request) (int, string) {
+		switch n {
+		case 1:
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[` + old + `]}}}}`
+		case 2:
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[` + old + `,{"JobType":"UpgradeVersion","JobStatus":"Running"}]}}}}`
+		default:
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[` + old + `,{"JobType":"UpgradeVersion","JobStatus":"Done"}]}}}}`
+		}
+	})
+
+	tracker := NewJobTracker(api.Client(), fastWait, nil)
+	jobs, err := tracker.WaitForJobs(t.Context(), "123", JobTypeIs(JobTypeUpgradeVersion))
+	assert.NoError(err)
+	assert.Equal([]Job{{Type: JobTypeUpgradeVersion, Status: JobStatusDone}}, jobs)
+	assert.Equal(3, api.Calls("GET /appliance/123/status"))
+}
+
+func TestJobTracker_WaitForJobs_UnknownStatus(t *testing.T) {
+	assert := require.New(t)
+
+	// 未知のステータスは実行中として待機を続ける
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/status", func(n int, _ *http.Request) (int, string) {
+		switch n {
+		case 1:
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[{"JobType":"Backup","JobStatus":"Running"}]}}}}`
+		case 2:
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[{"JobType":"Backup","JobStatus":"InProgress"}]}}}}`
+		default:
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[{"JobType":"Backup","JobStatus":"Done"}]}}}}`
+		}
+	})
+
+	tracker := NewJobTracker(api.Client(), fastWait, nil)
+	jobs, err := tracker.WaitForJobs(t.Context(), "123", nil)
+	assert.NoError(err)
+	assert.Equal([]Job{{Type: JobTypeBackup, Status: JobStatusDone}}, jobs)
+	assert.Equal(3, api.Calls("GET /appliance/123/status"))
+}
@@ -0,0 +1,88 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"net/http"
+	"time"
+
+	ht "github.com/ogen-go/ogen/http"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// Metrics API呼び出しと待機処理を計測するためのフック。並行して呼び出されるため実装はスレッドセーフである必要がある
+type Metrics interface {
+	// ObserveRequest v1.ClientのHTTPリクエスト毎に呼ばれる
+	ObserveRequest(RequestObservation)
+	// ObservePoll 待機処理のポーリング毎に呼ばれる
+	ObservePoll(PollObservation)
+}
+
+// RequestObservation HTTPリクエスト1回分の計測結果
+type RequestObservation struct {
+	// Operation ogenのオペレーション名。判定できない場合は空
+	Operation v1.OperationName
+	// StatusCode HTTPステータスコード。通信エラーなどでレスポンスがない場合は0
+	StatusCode int
+	// ErrorCode エラーレスポンスのerror_code
+	ErrorCode string
+	// Duration レスポンスヘッダーを受信するまでの時間
+	Duration time.Duration
+	// Err 通信エラー
+	Err error
+}
+
+// Failed エラーとして扱うリクエストかを返す
+func (o RequestObservation) Failed() bool {
+	return o.Err != nil || o.StatusCode >= http.StatusBadRequest
+}
+
+// PollObservation 待機処理のポーリング1回分の計測結果
+type PollObservation struct {
+	// Name 待機処理の名前(Database.WaitUntilAvailableなど)
+	Name string
+	// Attempt 何回目のポーリングか(1始まり)
+	Attempt int
+	// State ポーリングで確認した状態
+	State string
+}
+
+// WithMetrics API呼び出しと、DatabaseAPI/InstanceAPI/BackupAPIの待機処理の計測結果をmに渡す
+func WithMetrics(m Metrics) ClientOption {
+	return func(c *clientConfig) {
+		c.metrics = m
+		c.httpMiddlewares = append(c.httpMiddlewares, metricsMiddleware(m))
+	}
+}
+
+func metricsMiddleware(m Metrics) Middleware {
+	return MiddlewareFunc(func(operation v1.OperationName, req *http.Request, next ht.Client) (*http.Response, error) {
+		start := time.Now()
+		res, err := next.Do(req)
+		o := RequestObservation{Operation: operation, Duration: time.Since(start), Err: err}
+		if err == nil {
+			o.StatusCode = res.StatusCode
+			if res.StatusCode >= http.StatusBadRequest {
+				if body, ok := peekErrorResponse(res); ok {
+					o.ErrorCode = body.ErrorCode
+				}
+			}
+		}
+		m.ObserveRequest(o)
+		return res, err
+	})
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(RequestObservation) {}
+func (nopMetrics) ObservePoll(PollObservation)       {}
+
+// metricsOf クライアントに設定されたMetricsを返す
+func metricsOf(client *Client) Metrics {
+	if client.metrics != nil {
+		return client.metrics
+	}
+	return nopMetrics{}
+}
@@ -0,0 +1,121 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package prometheus nosql.MetricsをPrometheusのメトリクスとして記録するアダプタ
+package prometheus
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	nosql "github.com/sacloud/nosql-api-go"
+)
+
+const (
+	// DefaultNamespace メトリクス名のデフォルトのnamespace
+	DefaultNamespace = "sacloud"
+	// Subsystem メトリクス名のsubsystem
+	Subsystem = "nosql"
+)
+
+// Options Collectorの設定
+type Options struct {
+	// Namespace メトリクス名のnamespace。空の場合はDefaultNamespace
+	Namespace string
+	// Buckets レイテンシのヒストグラムのバケット(秒)。空の場合はprometheus.DefBuckets
+	Buckets []float64
+	// ConstLabels 全てのメトリクスに付与するラベル
+	ConstLabels prometheus.Labels
+}
+
+// Collector nosql.Metricsを実装し、計測結果をPrometheusのメトリクスとして公開するprometheus.Collector。
+//
+//   - <namespace>_nosql_requests_total{operation,status}
+//   - <namespace>_nosql_request_duration_seconds{operation}
+//   - <namespace>_nosql_request_errors_total{operation,status,error_code}
+//   - <namespace>_nosql_wait_polls_total{waiter}
+//
+// statusはHTTPステータスコードで、通信エラーの場合は"0"になる
+type Collector struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	polls    *prometheus.CounterVec
+}
+
+var _ nosql.Metrics = (*Collector)(nil)
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector Collectorを作成する。prometheus.Registerer.Registerで登録して利用する
+func NewCollector(opts *Options) *Collector {
+	if opts == nil {
+		opts = &Options{}
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	buckets := opts.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   Subsystem,
+			Name:        "requests_total",
+			Help:        "Number of NoSQL API requests.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"operation", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Subsystem:   Subsystem,
+			Name:        "request_duration_seconds",
+			Help:        "Latency of NoSQL API requests.",
+			Buckets:     buckets,
+			ConstLabels: opts.ConstLabels,
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   Subsystem,
+			Name:        "request_errors_total",
+			Help:        "Number of failed NoSQL API requests.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"operation", "status", "error_code"}),
+		polls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   Subsystem,
+			Name:        "wait_polls_total",
+			Help:        "Number of polling iterations of NoSQL waiters.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"waiter"}),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	c.errors.Describe(ch)
+	c.polls.Describe(ch)
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+	c.errors.Collect(ch)
+	c.polls.Collect(ch)
+}
+
+func (c *Collector) ObserveRequest(o nosql.RequestObservation) {
+	status := strconv.Itoa(o.StatusCode)
+	c.requests.WithLabelValues(o.Operation, status).Inc()
+	c.duration.WithLabelValues(o.Operation).Observe(o.Duration.Seconds())
+	if o.Failed() {
+		c.errors.WithLabelValues(o.Operation, status, o.ErrorCode).Inc()
+	}
+}
+
+func (c *Collector) ObservePoll(o nosql.PollObservation) {
+	c.polls.WithLabelValues(o.Name).Inc()
+}
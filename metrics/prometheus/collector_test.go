@@ -0,0 +1,51 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	nosql "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	nosqlprom "github.com/sacloud/nosql-api-go/metrics/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	assert := require.New(t)
+
+	collector := nosqlprom.NewCollector(&nosqlprom.Options{Buckets: []float64{0.1, 1}})
+	registry := prometheus.NewPedanticRegistry()
+	assert.NoError(registry.Register(collector))
+
+	collector.ObserveRequest(nosql.RequestObservation{Operation: v1.GetDBOperation, StatusCode: http.StatusOK, Duration: 50 * time.Millisecond})
+	collector.ObserveRequest(nosql.RequestObservation{Operation: v1.GetDBOperation, StatusCode: http.StatusOK, Duration: 500 * time.Millisecond})
+	collector.ObserveRequest(nosql.RequestObservation{Operation: v1.UpdateConfigDBOperation, StatusCode: http.StatusConflict, ErrorCode: "still_running", Duration: time.Millisecond})
+	collector.ObserveRequest(nosql.RequestObservation{Operation: v1.ListDBOperation, Err: errors.New("connection refused")})
+	collector.ObservePoll(nosql.PollObservation{Name: "Database.WaitUntilAvailable", Attempt: 1})
+	collector.ObservePoll(nosql.PollObservation{Name: "Database.WaitUntilAvailable", Attempt: 2})
+
+	assert.NoError(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP sacloud_nosql_request_errors_total Number of failed NoSQL API requests.
+# TYPE sacloud_nosql_request_errors_total counter
+sacloud_nosql_request_errors_total{error_code="",operation="ListDB",status="0"} 1
+sacloud_nosql_request_errors_total{error_code="still_running",operation="UpdateConfigDB",status="409"} 1
+# HELP sacloud_nosql_requests_total Number of NoSQL API requests.
+# TYPE sacloud_nosql_requests_total counter
+sacloud_nosql_requests_total{operation="GetDB",status="200"} 2
+sacloud_nosql_requests_total{operation="ListDB",status="0"} 1
+sacloud_nosql_requests_total{operation="UpdateConfigDB",status="409"} 1
+# HELP sacloud_nosql_wait_polls_total Number of polling iterations of NoSQL waiters.
+# TYPE sacloud_nosql_wait_polls_total counter
+sacloud_nosql_wait_polls_total{waiter="Database.WaitUntilAvailable"} 2
+`), "sacloud_nosql_requests_total", "sacloud_nosql_request_errors_total", "sacloud_nosql_wait_polls_total"))
+
+	assert.Equal(3, testutil.CollectAndCount(collector, "sacloud_nosql_request_duration_seconds"))
+}
@@ -0,0 +1,93 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	requests []RequestObservation
+	polls    []PollObservation
+}
+
+func (m *recordingMetrics) ObserveRequest(o RequestObservation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, o)
+}
+
+func (m *recordingMetrics) ObservePoll(o PollObservation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.polls = append(m.polls, o)
+}
+
+func TestWithMetrics(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		if n < 2 {
+			return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"migrating"}}`
+		}
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"available"}}`
+	})
+	api.Handle("PUT /appliance/123/config", func(int, *http.Request) (int, string) {
+		return http.StatusConflict, conflictJSON
+	})
+	metrics := &recordingMetrics{}
+	dbOp := NewDatabaseOp(api.Client(WithMetrics(metrics)))
+
+	_, err := dbOp.WaitUntilAvailable(t.Context(), "123", fastWait)
+	assert.NoError(err)
+	err = dbOp.ApplyChanges(t.Context(), "123")
+	assert.True(IsConflict(err))
+
+	assert.Len(metrics.requests, 3)
+	assert.Equal(v1.GetDBOperation, metrics.requests[0].Operation)
+	assert.Equal(http.StatusOK, metrics.requests[0].StatusCode)
+	assert.False(metrics.requests[0].Failed())
+
+	failed := metrics.requests[2]
+	assert.Equal(v1.UpdateConfigDBOperation, failed.Operation)
+	assert.Equal(http.StatusConflict, failed.StatusCode)
+	assert.Equal("still_running", failed.ErrorCode)
+	assert.True(failed.Failed())
+
+	assert.Equal([]PollObservation{
+		{Name: "Database.WaitUntilAvailable", Attempt: 1, State: "migrating"},
+		{Name: "Database.WaitUntilAvailable", Attempt: 2, State: "available"},
+	}, metrics.polls)
+}
+
+func TestWithMetrics_BackupOp(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/nosql/backup", func(n int, _ *http.Request) (int, string) {
+		if n <= 2 {
+			return http.StatusOK, `{"nosql":{"backups":[` + backupOld + `]},"is_ok":true}`
+		}
+		return http.StatusOK, `{"nosql":{"backups":[` + backupOld + `,` + backupNew + `]},"is_ok":true}`
+	})
+	api.Handle("POST /appliance/123/nosql/backup", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"is_ok":true}`
+	})
+	metrics := &recordingMetrics{}
+
+	_, err := NewBackupOp(api.Client(WithMetrics(metrics)), "123").CreateAndWait(t.Context(), fastWait)
+	assert.NoError(err)
+	assert.Len(metrics.requests, 4)
+	assert.Equal(v1.CreateBackupOperation, metrics.requests[1].Operation)
+	assert.Len(metrics.polls, 2)
+	assert.Equal("Backup.CreateAndWait", metrics.polls[1].Name)
+}
@@ -0,0 +1,96 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	ht "github.com/ogen-go/ogen/http"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// Middleware v1.Clientが送信するリクエストに割り込む処理。
+// operationはリクエストに対応するogenのオペレーション名で、判定できない場合は空になる
+type Middleware interface {
+	Do(operation v1.OperationName, req *http.Request, next ht.Client) (*http.Response, error)
+}
+
+// MiddlewareFunc 関数をMiddlewareとして使うためのアダプタ
+type MiddlewareFunc func(operation v1.OperationName, req *http.Request, next ht.Client) (*http.Response, error)
+
+func (f MiddlewareFunc) Do(operation v1.OperationName, req *http.Request, next ht.Client) (*http.Response, error) {
+	return f(operation, req, next)
+}
+
+// WithMiddleware v1.Clientにミドルウェアを追加する。先に指定したものほど外側(送信時に先)で呼ばれる
+func WithMiddleware(m ...Middleware) ClientOption {
+	return func(c *clientConfig) {
+		c.httpMiddlewares = append(c.httpMiddlewares, m...)
+	}
+}
+
+type middlewareClient struct {
+	middleware Middleware
+	next       ht.Client
+}
+
+func (c *middlewareClient) Do(req *http.Request) (*http.Response, error) {
+	return c.middleware.Do(operationNameOf(req), req, c.next)
+}
+
+// chainMiddlewares clientをミドルウェアで包む
+func chainMiddlewares(client ht.Client, middlewares []Middleware) ht.Client {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		client = &middlewareClient{middleware: middlewares[i], next: client}
+	}
+	return client
+}
+
+//go:generate go run ./internal/tools/gen-routes openapi/openapi.json operation_routes_gen.go
+
+type operationRouteTemplate struct {
+	method    string
+	path      string
+	operation v1.OperationName
+}
+
+// operationRoutes operationRouteTemplatesのパスを正規表現にしたもの
+var operationRoutes = func() []operationRoute {
+	ret := make([]operationRoute, len(operationRouteTemplates))
+	for i, r := range operationRouteTemplates {
+		ret[i] = operationRoute{method: r.method, path: routePattern(r.path), operation: r.operation}
+	}
+	return ret
+}()
+
+// routePattern openapi.jsonのパス({applianceID}などのパラメータを含む)にAPIルートURLより後ろが一致する正規表現を返す
+func routePattern(path string) *regexp.Regexp {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			segments[i] = `[^/]+`
+		} else {
+			segments[i] = regexp.QuoteMeta(s)
+		}
+	}
+	return regexp.MustCompile(strings.Join(segments, "/") + `$`)
+}
+
+type operationRoute struct {
+	method    string
+	path      *regexp.Regexp
+	operation v1.OperationName
+}
+
+// operationNameOf リクエストのメソッドとパスからオペレーション名を判定する。APIルートURLのパスは無視する
+func operationNameOf(req *http.Request) v1.OperationName {
+	for _, r := range operationRoutes {
+		if r.method == req.Method && r.path.MatchString(req.URL.Path) {
+			return r.operation
+		}
+	}
+	return ""
+}
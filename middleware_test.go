@@ -0,0 +1,53 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	ht "github.com/ogen-go/ogen/http"
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMiddleware(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	var mu sync.Mutex
+	var requestIDs []string
+	api.Handle("GET /appliance/123", func(_ int, r *http.Request) (int, string) {
+		mu.Lock()
+		requestIDs = append(requestIDs, r.Header.Get("X-Request-Id"))
+		mu.Unlock()
+		return http.StatusOK, `{"Appliance":{"ID":"123"}}`
+	})
+	api.Handle("PUT /appliance/123/nosql/backup/6e2b4c3a-0000-4000-8000-000000000001", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"is_ok":true}`
+	})
+
+	var calls []string
+	record := func(name string) Middleware {
+		return MiddlewareFunc(func(operation v1.OperationName, req *http.Request, next ht.Client) (*http.Response, error) {
+			calls = append(calls, name+":"+operation)
+			return next.Do(req)
+		})
+	}
+	injectID := MiddlewareFunc(func(_ v1.OperationName, req *http.Request, next ht.Client) (*http.Response, error) {
+		req.Header.Set("X-Request-Id", "req-1")
+		return next.Do(req)
+	})
+
+	client := api.Client(WithMiddleware(record("outer"), record("inner")), WithMiddleware(injectID))
+	_, err := NewDatabaseOp(client).Read(t.Context(), "123")
+	assert.NoError(err)
+	assert.NoError(NewBackupOp(client, "123").Restore(t.Context(), uuid.MustParse("6e2b4c3a-0000-4000-8000-000000000001")))
+
+	assert.Equal([]string{"outer:GetDB", "inner:GetDB", "outer:RestoreBackup", "inner:RestoreBackup"}, calls)
+	assert.Equal([]string{"req-1"}, requestIDs)
+}
@@ -0,0 +1,33 @@
+// Code generated by gen-routes from openapi/openapi.json, DO NOT EDIT.
+
+package nosql
+
+import (
+	"net/http"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// operationRouteTemplates openapi.jsonのパスとメソッドに対応するオペレーション名
+var operationRouteTemplates = []operationRouteTemplate{
+	{http.MethodGet, "/appliance", v1.ListDBOperation},
+	{http.MethodPost, "/appliance", v1.CreateDBOperation},
+	{http.MethodDelete, "/appliance/{applianceID}", v1.DeleteDBOperation},
+	{http.MethodGet, "/appliance/{applianceID}", v1.GetDBOperation},
+	{http.MethodPut, "/appliance/{applianceID}", v1.UpdateDBOperation},
+	{http.MethodPut, "/appliance/{applianceID}/config", v1.UpdateConfigDBOperation},
+	{http.MethodGet, "/appliance/{applianceID}/nosql/backup", v1.GetBackupByApplianceIDOperation},
+	{http.MethodPost, "/appliance/{applianceID}/nosql/backup", v1.CreateBackupOperation},
+	{http.MethodDelete, "/appliance/{applianceID}/nosql/backup/{backupID}", v1.DeleteBackupOperation},
+	{http.MethodPut, "/appliance/{applianceID}/nosql/backup/{backupID}", v1.RestoreBackupOperation},
+	{http.MethodGet, "/appliance/{applianceID}/nosql/nodes/health", v1.GetNoSQLNodeHealthOperation},
+	{http.MethodPost, "/appliance/{applianceID}/nosql/nodes/recover", v1.RecoverNoSQLNodeOperation},
+	{http.MethodGet, "/appliance/{applianceID}/nosql/parameter", v1.GetParameterOperation},
+	{http.MethodPut, "/appliance/{applianceID}/nosql/parameter", v1.PutParameterOperation},
+	{http.MethodPost, "/appliance/{applianceID}/nosql/repair", v1.PostNoSQLRepairOperation},
+	{http.MethodGet, "/appliance/{applianceID}/nosql/version", v1.GetVersionOperation},
+	{http.MethodPut, "/appliance/{applianceID}/nosql/version", v1.PutVersionOperation},
+	{http.MethodDelete, "/appliance/{applianceID}/power", v1.DeleteAppliancePowerOperation},
+	{http.MethodPut, "/appliance/{applianceID}/power", v1.PutAppliancePowerOperation},
+	{http.MethodGet, "/appliance/{applianceID}/status", v1.ConfirmStatusDBOperation},
+}
@@ -0,0 +1,62 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"regexp"
+	"strconv"
+	"testing"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// generatedOperations ogenが生成したv1.*Operation定数の値を返す
+func generatedOperations(t *testing.T) []v1.OperationName {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "apis/v1/oas_operations_gen.go", nil, 0)
+	require.NoError(t, err)
+
+	var ret []v1.OperationName
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		for _, value := range spec.Values {
+			if lit, ok := value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				name, err := strconv.Unquote(lit.Value)
+				require.NoError(t, err)
+				ret = append(ret, name)
+			}
+		}
+		return true
+	})
+	require.NotEmpty(t, ret)
+	return ret
+}
+
+func TestOperationRoutes(t *testing.T) {
+	assert := require.New(t)
+
+	params := regexp.MustCompile(`\{[^}]+\}`)
+	for _, operation := range generatedOperations(t) {
+		var found bool
+		for _, r := range operationRouteTemplates {
+			if r.operation != operation {
+				continue
+			}
+			found = true
+
+			req, err := http.NewRequest(r.method, "https://secure.sakura.ad.jp/cloud/zone/tk1b/api/cloud/1.1"+params.ReplaceAllString(r.path, "123"), nil)
+			assert.NoError(err)
+			assert.Equal(operation, operationNameOf(req), "%s %s", r.method, r.path)
+		}
+		assert.True(found, "no route for %s", operation)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+// OpOption NewDatabaseOpなどで作成するオペレーションの設定
+type OpOption func(*opConfig)
+
+type opConfig struct {
+	retry *RetryPolicy
+}
+
+func newOpConfig(opts []OpOption) opConfig {
+	var c opConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// options 内部で別のオペレーションを作成する際に設定を引き継ぐ
+func (c opConfig) options() []OpOption {
+	return []OpOption{func(o *opConfig) { *o = c }}
+}
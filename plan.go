@@ -13,136 +13,196 @@ const (
 	Plan250GB Plan = "250GB"
 )
 
+// PlanSpec プランの属性。ノード追加に対応しないプランではNodesで始まる項目とMaxNodeGroupsがゼロ値になる
+type PlanSpec struct {
+	Plan         Plan            `json:"plan" yaml:"plan"`
+	ID           int             `json:"id" yaml:"id"`
+	ServiceClass v1.ServiceClass `json:"service_class" yaml:"service_class"`
+	MemoryMB     int             `json:"memory_mb" yaml:"memory_mb"`
+	DiskSizeMB   int             `json:"disk_size_mb" yaml:"disk_size_mb"`
+	VirtualCore  int             `json:"virtual_core" yaml:"virtual_core"`
+	Nodes        int             `json:"nodes" yaml:"nodes"`
+
+	// NodesID ノード追加用のプランID
+	NodesID int `json:"nodes_id,omitempty" yaml:"nodes_id,omitempty"`
+	// NodesServiceClass ノード追加用のサービスクラス
+	NodesServiceClass v1.ServiceClass `json:"nodes_service_class,omitempty" yaml:"nodes_service_class,omitempty"`
+	// NodesPerGroup 1回のノード追加で増えるノード数
+	NodesPerGroup int `json:"nodes_per_group,omitempty" yaml:"nodes_per_group,omitempty"`
+	// MaxNodeGroups ノード追加を行える回数の上限
+	MaxNodeGroups int `json:"max_node_groups,omitempty" yaml:"max_node_groups,omitempty"`
+}
+
+var defaultPlanCatalog = []PlanSpec{
+	{
+		Plan:         Plan40GB,
+		ID:           51142,
+		ServiceClass: "cloud/nosql/plan/1",
+		MemoryMB:     4096,
+		DiskSizeMB:   40960,
+		VirtualCore:  2,
+		Nodes:        1,
+	},
+	{
+		Plan:              Plan100GB,
+		ID:                51143,
+		ServiceClass:      "cloud/nosql/plan/2",
+		MemoryMB:          8192,
+		DiskSizeMB:        102400,
+		VirtualCore:       3,
+		Nodes:             3,
+		NodesID:           51145,
+		NodesServiceClass: "cloud/nosql/plan/2/node",
+		NodesPerGroup:     2,
+		MaxNodeGroups:     3,
+	},
+	{
+		Plan:              Plan250GB,
+		ID:                51144,
+		ServiceClass:      "cloud/nosql/plan/3",
+		MemoryMB:          16384,
+		DiskSizeMB:        256000,
+		VirtualCore:       6,
+		Nodes:             3,
+		NodesID:           51146,
+		NodesServiceClass: "cloud/nosql/plan/3/node",
+		NodesPerGroup:     2,
+		MaxNodeGroups:     3,
+	},
+}
+
+// PlanSpecs 全てのプランの属性を返す
+func PlanSpecs() []PlanSpec {
+	catalog := currentPlanCatalog()
+	ret := make([]PlanSpec, len(catalog))
+	copy(ret, catalog)
+	return ret
+}
+
+// GetPlanSpecFromID プランIDからプランの属性を返す。ノード追加用のプランIDの場合はforNodesがtrueになる
+func GetPlanSpecFromID(planID int) (spec PlanSpec, forNodes bool, ok bool) {
+	for _, s := range currentPlanCatalog() {
+		switch planID {
+		case s.ID:
+			return s, false, true
+		case s.NodesID:
+			if s.NodesID != 0 {
+				return s, true, true
+			}
+		}
+	}
+	return PlanSpec{}, false, false
+}
+
+// GetPlanSpecFromServiceClass サービスクラスからプランの属性を返す。ノード追加用のサービスクラスの場合はforNodesがtrueになる
+func GetPlanSpecFromServiceClass(serviceClass string) (spec PlanSpec, forNodes bool, ok bool) {
+	for _, s := range currentPlanCatalog() {
+		switch v1.ServiceClass(serviceClass) {
+		case s.ServiceClass:
+			return s, false, true
+		case s.NodesServiceClass:
+			if s.NodesServiceClass != "" {
+				return s, true, true
+			}
+		}
+	}
+	return PlanSpec{}, false, false
+}
+
+// GetPlanSpecFromResources メモリ・ディスクサイズ・仮想コア数が一致するプランの属性を返す
+func GetPlanSpecFromResources(memoryMB, diskSizeMB, virtualCore int) (PlanSpec, bool) {
+	for _, s := range currentPlanCatalog() {
+		if s.MemoryMB == memoryMB && s.DiskSizeMB == diskSizeMB && s.VirtualCore == virtualCore {
+			return s, true
+		}
+	}
+	return PlanSpec{}, false
+}
+
 func GetPlanFromID(planID int) Plan {
-	switch planID {
-	case 51142:
-		return Plan40GB
-	case 51143, 51145:
-		return Plan100GB
-	case 51144, 51146:
-		return Plan250GB
-	default:
-		return ""
+	spec, _, _ := GetPlanSpecFromID(planID)
+	return spec.Plan
+}
+
+// GetPlanFromServiceClass サービスクラス(ノード追加用を含む)からプランを返す
+func GetPlanFromServiceClass(serviceClass string) Plan {
+	spec, _, _ := GetPlanSpecFromServiceClass(serviceClass)
+	return spec.Plan
+}
+
+// Spec プランの属性を返す
+func (p Plan) Spec() (PlanSpec, bool) {
+	for _, s := range currentPlanCatalog() {
+		if s.Plan == p {
+			return s, true
+		}
 	}
+	return PlanSpec{}, false
+}
+
+func (p Plan) spec() PlanSpec {
+	s, _ := p.Spec()
+	return s
 }
 
 func (Plan) AllValues() []Plan {
-	return []Plan{
-		Plan40GB,
-		Plan100GB,
-		Plan250GB,
+	catalog := currentPlanCatalog()
+	ret := make([]Plan, 0, len(catalog))
+	for _, s := range catalog {
+		ret = append(ret, s.Plan)
 	}
+	return ret
 }
 
 func (Plan) AllValuesAsString() []string {
-	return []string{
-		string(Plan40GB),
-		string(Plan100GB),
-		string(Plan250GB),
+	catalog := currentPlanCatalog()
+	ret := make([]string, 0, len(catalog))
+	for _, s := range catalog {
+		ret = append(ret, string(s.Plan))
 	}
+	return ret
 }
 
 func (p Plan) GetPlanID() int {
-	switch p {
-	case Plan40GB:
-		return 51142
-	case Plan100GB:
-		return 51143
-	case Plan250GB:
-		return 51144
-	default:
-		return 0
-	}
+	return p.spec().ID
 }
 
 func (p Plan) GetPlanIDforNodes() int {
-	switch p {
-	case Plan100GB:
-		return 51145
-	case Plan250GB:
-		return 51146
-	default:
-		return 0
-	}
+	return p.spec().NodesID
 }
 
 func (p Plan) GetServiceClass() v1.ServiceClass {
-	switch p {
-	case Plan40GB:
-		return v1.ServiceClass("cloud/nosql/plan/1")
-	case Plan100GB:
-		return v1.ServiceClass("cloud/nosql/plan/2")
-	case Plan250GB:
-		return v1.ServiceClass("cloud/nosql/plan/3")
-	default:
-		return ""
-	}
+	return p.spec().ServiceClass
 }
 
 func (p Plan) GetServiceClassForNodes() v1.ServiceClass {
-	switch p {
-	case Plan100GB:
-		return v1.ServiceClass("cloud/nosql/plan/2/node")
-	case Plan250GB:
-		return v1.ServiceClass("cloud/nosql/plan/3/node")
-	default:
-		return ""
-	}
+	return p.spec().NodesServiceClass
 }
 
 func (p Plan) GetMemoryMB() int {
-	switch p {
-	case Plan40GB:
-		return 4096
-	case Plan100GB:
-		return 8192
-	case Plan250GB:
-		return 16384
-	default:
-		return 0
-	}
+	return p.spec().MemoryMB
 }
 
 func (p Plan) GetDiskSizeMB() int {
-	switch p {
-	case Plan40GB:
-		return 40960
-	case Plan100GB:
-		return 102400
-	case Plan250GB:
-		return 256000
-	default:
-		return 0
-	}
+	return p.spec().DiskSizeMB
 }
 
 func (p Plan) GetVirtualCore() int {
-	switch p {
-	case Plan40GB:
-		return 2
-	case Plan100GB:
-		return 3
-	case Plan250GB:
-		return 6
-	default:
-		return 0
-	}
+	return p.spec().VirtualCore
 }
 
 func (p Plan) GetNodes() int {
-	switch p {
-	case Plan100GB, Plan250GB:
-		return 3
-	default:
-		return 1
+	if s, ok := p.Spec(); ok {
+		return s.Nodes
 	}
+	return 1
 }
 
 func (p Plan) GetNodesForNodes() int {
-	switch p {
-	case Plan100GB, Plan250GB:
-		return 2
-	default:
-		return 0
-	}
+	return p.spec().NodesPerGroup
+}
+
+// GetMaxNodeGroups ノード追加を行える回数の上限を返す
+func (p Plan) GetMaxNodeGroups() int {
+	return p.spec().MaxNodeGroups
 }
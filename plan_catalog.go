@@ -0,0 +1,141 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// PlanCatalogDocument LoadPlanCatalogで読み込むJSON/YAMLドキュメントの形式
+//
+//	plans:
+//	  - plan: 100GB
+//	    id: 51143
+//	    service_class: cloud/nosql/plan/2
+//	    memory_mb: 8192
+//	    disk_size_mb: 102400
+//	    virtual_core: 3
+//	    nodes: 3
+//	    nodes_id: 51145
+//	    nodes_service_class: cloud/nosql/plan/2/node
+//	    nodes_per_group: 2
+//	    max_node_groups: 3
+type PlanCatalogDocument struct {
+	Plans []PlanSpec `json:"plans" yaml:"plans"`
+}
+
+var planCatalog atomic.Pointer[[]PlanSpec]
+
+func currentPlanCatalog() []PlanSpec {
+	if c := planCatalog.Load(); c != nil {
+		return *c
+	}
+	return defaultPlanCatalog
+}
+
+// LoadPlanCatalog JSONまたはYAMLのドキュメントからプランの一覧を読み込み、検証する
+func LoadPlanCatalog(data []byte) ([]PlanSpec, error) {
+	var doc PlanCatalogDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, NewError("unable to parse plan catalog", err)
+	}
+	if err := ValidatePlanCatalog(doc.Plans); err != nil {
+		return nil, err
+	}
+	return doc.Plans, nil
+}
+
+// SetPlanCatalog プランの一覧を置き換える。Planの各メソッドやDatabaseAPI.Createは置き換えた一覧を参照する
+func SetPlanCatalog(specs []PlanSpec) error {
+	if err := ValidatePlanCatalog(specs); err != nil {
+		return err
+	}
+	c := make([]PlanSpec, len(specs))
+	copy(c, specs)
+	planCatalog.Store(&c)
+	return nil
+}
+
+// ExtendPlanCatalog 現在のプランの一覧に追加する。同じPlanのものは置き換える
+func ExtendPlanCatalog(specs []PlanSpec) error {
+	merged := PlanSpecs()
+	for _, s := range specs {
+		replaced := false
+		for i := range merged {
+			if merged[i].Plan == s.Plan {
+				merged[i] = s
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, s)
+		}
+	}
+	return SetPlanCatalog(merged)
+}
+
+// ResetPlanCatalog プランの一覧を組み込みのものに戻す
+func ResetPlanCatalog() {
+	planCatalog.Store(nil)
+}
+
+// ValidatePlanCatalog プランの一覧に必須項目の欠落やPlan・プランID・サービスクラスの重複がないか検証する
+func ValidatePlanCatalog(specs []PlanSpec) error {
+	if len(specs) == 0 {
+		return NewError("invalid plan catalog", errors.New("no plans"))
+	}
+
+	var errs []error
+	plans := map[Plan]struct{}{}
+	ids := map[int]Plan{}
+	classes := map[v1.ServiceClass]Plan{}
+	for _, s := range specs {
+		if s.Plan == "" || s.ID == 0 || s.ServiceClass == "" || s.Nodes <= 0 {
+			errs = append(errs, fmt.Errorf("plan %q: plan, id, service_class and nodes are required", s.Plan))
+			continue
+		}
+		if _, ok := plans[s.Plan]; ok {
+			errs = append(errs, fmt.Errorf("plan %q: duplicate plan", s.Plan))
+		}
+		plans[s.Plan] = struct{}{}
+
+		checkID := func(id int) {
+			if p, ok := ids[id]; ok {
+				errs = append(errs, fmt.Errorf("plan %q: id %d is already used by plan %q", s.Plan, id, p))
+			}
+			ids[id] = s.Plan
+		}
+		checkClass := func(sc v1.ServiceClass) {
+			if p, ok := classes[sc]; ok {
+				errs = append(errs, fmt.Errorf("plan %q: service class %q is already used by plan %q", s.Plan, sc, p))
+			}
+			classes[sc] = s.Plan
+		}
+		checkID(s.ID)
+		checkClass(s.ServiceClass)
+
+		if s.NodesID == 0 {
+			if s.NodesServiceClass != "" || s.NodesPerGroup != 0 || s.MaxNodeGroups != 0 {
+				errs = append(errs, fmt.Errorf("plan %q: nodes_id is required to add nodes", s.Plan))
+			}
+			continue
+		}
+		if s.NodesServiceClass == "" || s.NodesPerGroup <= 0 || s.MaxNodeGroups <= 0 {
+			errs = append(errs, fmt.Errorf("plan %q: nodes_service_class, nodes_per_group and max_node_groups are required with nodes_id", s.Plan))
+			continue
+		}
+		checkID(s.NodesID)
+		checkClass(s.NodesServiceClass)
+	}
+	if len(errs) > 0 {
+		return NewError("invalid plan catalog", errors.Join(errs...))
+	}
+	return nil
+}
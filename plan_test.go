@@ -90,3 +90,128 @@ func TestPlan_ForNodesAttributes(t *testing.T) {
 		})
 	}
 }
+
+func TestPlan_ReverseLookups(t *testing.T) {
+	cases := []struct {
+		name         string
+		planID       int
+		serviceClass string
+		want         nosql.Plan
+		forNodes     bool
+	}{
+		{"40GB", 51142, "cloud/nosql/plan/1", nosql.Plan40GB, false},
+		{"100GB", 51143, "cloud/nosql/plan/2", nosql.Plan100GB, false},
+		{"100GB/node", 51145, "cloud/nosql/plan/2/node", nosql.Plan100GB, true},
+		{"250GB", 51144, "cloud/nosql/plan/3", nosql.Plan250GB, false},
+		{"250GB/node", 51146, "cloud/nosql/plan/3/node", nosql.Plan250GB, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := nosql.GetPlanFromID(tc.planID); got != tc.want {
+				t.Errorf("GetPlanFromID(%d) = %s, want %s", tc.planID, got, tc.want)
+			}
+			spec, forNodes, ok := nosql.GetPlanSpecFromID(tc.planID)
+			if !ok || spec.Plan != tc.want || forNodes != tc.forNodes {
+				t.Errorf("GetPlanSpecFromID(%d) = %v, %v, %v", tc.planID, spec.Plan, forNodes, ok)
+			}
+			if got := nosql.GetPlanFromServiceClass(tc.serviceClass); got != tc.want {
+				t.Errorf("GetPlanFromServiceClass(%s) = %s, want %s", tc.serviceClass, got, tc.want)
+			}
+			spec, forNodes, ok = nosql.GetPlanSpecFromServiceClass(tc.serviceClass)
+			if !ok || spec.Plan != tc.want || forNodes != tc.forNodes {
+				t.Errorf("GetPlanSpecFromServiceClass(%s) = %v, %v, %v", tc.serviceClass, spec.Plan, forNodes, ok)
+			}
+		})
+	}
+
+	if got := nosql.GetPlanFromID(0); got != "" {
+		t.Errorf("GetPlanFromID(0) = %s, want empty", got)
+	}
+	if got := nosql.GetPlanFromServiceClass(""); got != "" {
+		t.Errorf("GetPlanFromServiceClass(\"\") = %s, want empty", got)
+	}
+	if spec, ok := nosql.GetPlanSpecFromResources(8192, 102400, 3); !ok || spec.Plan != nosql.Plan100GB {
+		t.Errorf("GetPlanSpecFromResources(8192, 102400, 3) = %v, %v", spec.Plan, ok)
+	}
+	if _, ok := nosql.GetPlanSpecFromResources(1, 2, 3); ok {
+		t.Errorf("GetPlanSpecFromResources(1, 2, 3) should not match")
+	}
+}
+
+func TestLoadPlanCatalog(t *testing.T) {
+	t.Cleanup(nosql.ResetPlanCatalog)
+
+	yamlDoc := `
+plans:
+  - plan: 40GB
+    id: 51142
+    service_class: cloud/nosql/plan/1
+    memory_mb: 4096
+    disk_size_mb: 40960
+    virtual_core: 2
+    nodes: 1
+  - plan: 500GB
+    id: 60000
+    service_class: cloud/nosql/plan/4
+    memory_mb: 32768
+    disk_size_mb: 512000
+    virtual_core: 8
+    nodes: 3
+    nodes_id: 60001
+    nodes_service_class: cloud/nosql/plan/4/node
+    nodes_per_group: 2
+    max_node_groups: 3
+`
+	specs, err := nosql.LoadPlanCatalog([]byte(yamlDoc))
+	if err != nil {
+		t.Fatalf("LoadPlanCatalog() error = %v", err)
+	}
+	if err := nosql.SetPlanCatalog(specs); err != nil {
+		t.Fatalf("SetPlanCatalog() error = %v", err)
+	}
+
+	p := nosql.Plan("500GB")
+	if got := p.GetPlanID(); got != 60000 {
+		t.Errorf("GetPlanID(500GB) = %d, want 60000", got)
+	}
+	if got := p.GetPlanIDforNodes(); got != 60001 {
+		t.Errorf("GetPlanIDforNodes(500GB) = %d, want 60001", got)
+	}
+	if got := nosql.GetPlanFromServiceClass("cloud/nosql/plan/4/node"); got != p {
+		t.Errorf("GetPlanFromServiceClass() = %s, want %s", got, p)
+	}
+	if got := nosql.Plan100GB.GetPlanID(); got != 0 {
+		t.Errorf("GetPlanID(100GB) = %d, want 0 after replacing the catalog", got)
+	}
+	want := []nosql.Plan{nosql.Plan40GB, p}
+	if got := p.AllValues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AllValues() = %v, want %v", got, want)
+	}
+
+	nosql.ResetPlanCatalog()
+	if err := nosql.ExtendPlanCatalog(specs[1:]); err != nil {
+		t.Fatalf("ExtendPlanCatalog() error = %v", err)
+	}
+	if got := len(p.AllValues()); got != 4 {
+		t.Errorf("len(AllValues()) = %d, want 4 after extending the catalog", got)
+	}
+}
+
+func TestLoadPlanCatalog_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"duplicate id":       `{"plans":[{"plan":"a","id":1,"service_class":"x/1","nodes":1},{"plan":"b","id":1,"service_class":"x/2","nodes":1}]}`,
+		"duplicate nodes id": `{"plans":[{"plan":"a","id":1,"service_class":"x/1","nodes":3,"nodes_id":1,"nodes_service_class":"x/1/node","nodes_per_group":2,"max_node_groups":3}]}`,
+		"duplicate plan":     `{"plans":[{"plan":"a","id":1,"service_class":"x/1","nodes":1},{"plan":"a","id":2,"service_class":"x/2","nodes":1}]}`,
+		"missing fields":     `{"plans":[{"plan":"a"}]}`,
+		"empty":              `{"plans":[]}`,
+		"malformed":          `{"plans":`,
+	}
+	for name, doc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := nosql.LoadPlanCatalog([]byte(doc)); err == nil {
+				t.Errorf("LoadPlanCatalog() should fail")
+			}
+		})
+	}
+}
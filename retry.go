@@ -0,0 +1,136 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+const (
+	// DefaultRetryMaxAttempts 初回を含めた最大試行回数のデフォルト値
+	DefaultRetryMaxAttempts = 3
+	// DefaultRetryInterval 初回のリトライまでの待機時間のデフォルト値
+	DefaultRetryInterval = 5 * time.Second
+	// DefaultRetryMaxInterval リトライまでの待機時間の上限のデフォルト値
+	DefaultRetryMaxInterval = time.Minute
+)
+
+// IdempotentOperations 繰り返し実行しても結果が変わらないため、デフォルトでリトライの対象となるオペレーション。
+// UpdateConfigDB(ApplyChanges)は反映のジョブを開始するため含まない
+func IdempotentOperations() []v1.OperationName {
+	return []v1.OperationName{
+		v1.ListDBOperation,
+		v1.GetDBOperation,
+		v1.UpdateDBOperation,
+		v1.ConfirmStatusDBOperation,
+		v1.GetVersionOperation,
+		v1.GetParameterOperation,
+		v1.PutParameterOperation,
+		v1.GetNoSQLNodeHealthOperation,
+		v1.PutAppliancePowerOperation,
+		v1.DeleteAppliancePowerOperation,
+		v1.GetBackupByApplianceIDOperation,
+	}
+}
+
+// RetryPolicy 409(他のジョブの実行中)や5xxが返された場合のリトライの設定。ゼロ値の項目はデフォルト値を使う
+type RetryPolicy struct {
+	// MaxAttempts 初回を含めた最大試行回数
+	MaxAttempts int
+	// Interval 初回のリトライまでの待機時間
+	Interval time.Duration
+	// MaxInterval 待機時間の上限
+	MaxInterval time.Duration
+	// Backoff リトライ毎に待機時間に掛ける係数。1未満の場合は2
+	Backoff float64
+	// Jitter 待機時間をランダムに短くする割合(0〜1)。0の場合は短くしない
+	Jitter float64
+	// Operations リトライの対象とするオペレーション。nilの場合はIdempotentOperationsを使う。
+	// 冪等でないオペレーション(CreateDBなど)はここに明示した場合のみリトライする
+	Operations []v1.OperationName
+}
+
+// WithRetryPolicy APIが409や5xxを返した場合にpolicyに従ってリトライする
+func WithRetryPolicy(policy *RetryPolicy) OpOption {
+	return func(c *opConfig) {
+		c.retry = policy
+	}
+}
+
+func (p *RetryPolicy) allows(operation v1.OperationName) bool {
+	operations := p.Operations
+	if operations == nil {
+		operations = IdempotentOperations()
+	}
+	return slices.Contains(operations, operation)
+}
+
+// delay attempt回目の試行が失敗した後の待機時間
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultRetryInterval
+	}
+	maxInterval := p.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = DefaultRetryMaxInterval
+	}
+	backoff := p.Backoff
+	if backoff < 1 {
+		backoff = 2
+	}
+
+	d := float64(interval)
+	for i := 1; i < attempt && d < float64(maxInterval); i++ {
+		d *= backoff
+	}
+	d = min(d, float64(maxInterval))
+	if p.Jitter > 0 {
+		d -= d * min(p.Jitter, 1) * rand.Float64() //nolint:gosec
+	}
+	return time.Duration(d)
+}
+
+// retryableResult APIの呼び出し結果がリトライで解消する可能性のあるものか
+func retryableResult(res any, err error) bool {
+	if err != nil {
+		return IsRetryable(NewAPIError("", 0, err))
+	}
+	switch res.(type) {
+	case *v1.ConflictErrorResponse, *v1.ServerErrorResponse:
+		return true
+	}
+	return false
+}
+
+// callWithRetry policyに従ってcallをリトライする。policyがnilまたはoperationが対象外の場合は1度だけ呼び出す
+func callWithRetry[T any](ctx context.Context, policy *RetryPolicy, operation v1.OperationName, call func(context.Context) (T, error)) (T, error) {
+	if policy == nil || !policy.allows(operation) {
+		return call(ctx)
+	}
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultRetryMaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		res, err := call(ctx)
+		if attempt >= maxAttempts || !retryableResult(res, err) {
+			return res, err
+		}
+
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, err
+		case <-timer.C:
+		}
+	}
+}
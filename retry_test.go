@@ -0,0 +1,121 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+const conflictJSON = `{"is_fatal":true,"status":"409 Conflict","error_code":"still_running","error_msg":"別のジョブを実行中です。"}`
+
+var fastRetry = &RetryPolicy{MaxAttempts: 3, Interval: time.Millisecond, Jitter: 0.5}
+
+func TestRetryPolicy_Conflict(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("PUT /appliance/123", func(n int, _ *http.Request) (int, string) {
+		if n < 3 {
+			return http.StatusConflict, conflictJSON
+		}
+		return http.StatusOK, `{"Success":true,"is_ok":true}`
+	})
+
+	err := NewDatabaseOp(api.Client(), WithRetryPolicy(fastRetry)).Update(t.Context(), "123", v1.NosqlUpdateRequestAppliance{})
+	assert.NoError(err)
+	assert.Equal(3, api.Calls("PUT /appliance/123"))
+}
+
+func TestRetryPolicy_MaxAttempts(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("PUT /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusConflict, conflictJSON
+	})
+
+	err := NewDatabaseOp(api.Client(), WithRetryPolicy(fastRetry)).Update(t.Context(), "123", v1.NosqlUpdateRequestAppliance{})
+	assert.True(IsConflict(err))
+	assert.Equal(3, api.Calls("PUT /appliance/123"))
+}
+
+func TestRetryPolicy_Disabled(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("PUT /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusConflict, conflictJSON
+	})
+
+	err := NewDatabaseOp(api.Client()).Update(t.Context(), "123", v1.NosqlUpdateRequestAppliance{})
+	assert.True(IsConflict(err))
+	assert.Equal(1, api.Calls("PUT /appliance/123"))
+}
+
+func TestRetryPolicy_NonIdempotent(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("POST /appliance", func(n int, _ *http.Request) (int, string) {
+		if n == 1 {
+			return http.StatusConflict, conflictJSON
+		}
+		return http.StatusAccepted, `{"Appliance":{"ID":"123","Availability":"migrating"},"Success":true,"is_ok":true}`
+	})
+
+	// 冪等でないCreateDBはデフォルトではリトライしない
+	_, err := NewDatabaseOp(api.Client(), WithRetryPolicy(fastRetry)).Create(t.Context(), Plan100GB, clusterApplianceRequest("nosql"))
+	assert.True(IsConflict(err))
+	assert.Equal(1, api.Calls("POST /appliance"))
+
+	optIn := *fastRetry
+	optIn.Operations = []v1.OperationName{v1.CreateDBOperation}
+	_, err = NewDatabaseOp(api.Client(), WithRetryPolicy(&optIn)).Create(t.Context(), Plan100GB, clusterApplianceRequest("nosql"))
+	assert.NoError(err)
+	assert.Equal(2, api.Calls("POST /appliance"))
+}
+
+func TestRetryPolicy_ApplyChanges(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("PUT /appliance/123/config", func(n int, _ *http.Request) (int, string) {
+		if n == 1 {
+			return http.StatusConflict, conflictJSON
+		}
+		return http.StatusOK, `{"Success":true,"is_ok":true}`
+	})
+
+	// 反映のジョブを二重に開始しないよう、ApplyChangesはデフォルトではリトライしない
+	err := NewDatabaseOp(api.Client(), WithRetryPolicy(fastRetry)).ApplyChanges(t.Context(), "123")
+	assert.True(IsConflict(err))
+	assert.Equal(1, api.Calls("PUT /appliance/123/config"))
+
+	optIn := *fastRetry
+	optIn.Operations = []v1.OperationName{v1.UpdateConfigDBOperation}
+	assert.NoError(NewDatabaseOp(api.Client(), WithRetryPolicy(&optIn)).ApplyChanges(t.Context(), "123"))
+	assert.Equal(2, api.Calls("PUT /appliance/123/config"))
+}
+
+func TestRetryPolicy_Canceled(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/nosql/backup", func(int, *http.Request) (int, string) {
+		return http.StatusConflict, conflictJSON
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	_, err := NewBackupOp(api.Client(), "123", WithRetryPolicy(&RetryPolicy{Interval: time.Hour})).List(ctx)
+	assert.True(IsConflict(err))
+	assert.Equal(1, api.Calls("GET /appliance/123/nosql/backup"))
+}
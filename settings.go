@@ -0,0 +1,91 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"bytes"
+	"fmt"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// SettingsConflictError UpdateAndApplyの途中で他の更新によりSettingsHashが変わったことを示すエラー
+type SettingsConflictError struct {
+	ID           string
+	ExpectedHash string
+	ActualHash   string
+}
+
+func (e *SettingsConflictError) Error() string {
+	return fmt.Sprintf("settings of appliance %s were modified concurrently (SettingsHash %q -> %q)", e.ID, e.ExpectedHash, e.ActualHash)
+}
+
+// NosqlSettingsFromGet Readで取得した設定をUpdateに渡せる形に変換する。
+// Passwordは取得できないため設定されない
+func NosqlSettingsFromGet(s v1.GetNosqlSettings) v1.NosqlSettings {
+	ret := v1.NosqlSettings{
+		ReserveIPAddress: s.ReserveIPAddress,
+		SourceNetwork:    s.SourceNetwork,
+	}
+
+	if b, ok := s.Backup.Get(); ok {
+		backup := v1.NosqlSettingsBackup{
+			Connect: b.Connect.Value,
+			Time:    b.Time,
+			Rotate:  b.Rotate.Value,
+		}
+		if days, ok := b.DayOfWeek.Get(); ok {
+			items := make([]v1.NosqlSettingsBackupDayOfWeekItem, 0, len(days))
+			for _, d := range days {
+				items = append(items, v1.NosqlSettingsBackupDayOfWeekItem(d))
+			}
+			backup.DayOfWeek = v1.NewOptNilNosqlSettingsBackupDayOfWeekItemArray(items)
+		}
+		ret.Backup = v1.NewOptNilNosqlSettingsBackup(backup)
+	}
+
+	if r, ok := s.Repair.Get(); ok {
+		var repair v1.NosqlSettingsRepair
+		if inc, ok := r.Incremental.Get(); ok {
+			days := make([]v1.NosqlSettingsRepairIncrementalDaysOfWeekItem, 0, len(inc.DaysOfWeek))
+			for _, d := range inc.DaysOfWeek {
+				days = append(days, v1.NosqlSettingsRepairIncrementalDaysOfWeekItem(d))
+			}
+			repair.Incremental = v1.NewOptNosqlSettingsRepairIncremental(v1.NosqlSettingsRepairIncremental{
+				DaysOfWeek: days,
+				Time:       inc.Time.Value,
+			})
+		}
+		if full, ok := r.Full.Get(); ok {
+			repair.Full = v1.NewOptNosqlSettingsRepairFull(v1.NosqlSettingsRepairFull{
+				Interval:  v1.NosqlSettingsRepairFullInterval(full.Interval.Value),
+				DayOfWeek: v1.NosqlSettingsRepairFullDayOfWeek(full.DayOfWeek.Value),
+				Time:      full.Time.Value,
+			})
+		}
+		ret.Repair = v1.NewOptNilNosqlSettingsRepair(repair)
+	}
+
+	return ret
+}
+
+// sameSettings Passwordを除いた設定が同じかどうか
+func sameSettings(a, b v1.NosqlSettings) bool {
+	normalize := func(s v1.NosqlSettings) ([]byte, error) {
+		s.Password = v1.OptPassword{}
+		if s.SourceNetwork == nil {
+			s.SourceNetwork = []string{}
+		}
+		return s.MarshalJSON()
+	}
+	aj, err := normalize(a)
+	if err != nil {
+		return false
+	}
+	bj, err := normalize(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aj, bj)
+}
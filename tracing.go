@@ -0,0 +1,139 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+
+	ht "github.com/ogen-go/ogen/http"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TracerName トレースで使うTracerの名前
+const TracerName = "github.com/sacloud/nosql-api-go"
+
+// スパンに付与する属性
+const (
+	AttributeApplianceID    = attribute.Key("nosql.appliance_id")
+	AttributeOperation      = attribute.Key("nosql.operation")
+	AttributeErrorSerial    = attribute.Key("nosql.error.serial")
+	AttributeErrorCode      = attribute.Key("nosql.error.code")
+	AttributeHTTPMethod     = attribute.Key("http.request.method")
+	AttributeHTTPStatusCode = attribute.Key("http.response.status_code")
+)
+
+var applianceIDInPath = regexp.MustCompile(`/appliance/([^/]+)`)
+
+// WithTracerProvider OpenTelemetryのトレースを有効にする。
+// API呼び出し毎にオペレーション名のスパンを作成し、待機処理などの複数のAPI呼び出しからなる処理ではその親となるスパンを作成する
+func WithTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(c *clientConfig) {
+		c.tracerProvider = tp
+		c.httpMiddlewares = append(c.httpMiddlewares, tracingMiddleware(newTracer(tp)))
+	}
+}
+
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return tp.Tracer(TracerName, trace.WithInstrumentationVersion(Version))
+}
+
+func tracingMiddleware(tracer trace.Tracer) Middleware {
+	return MiddlewareFunc(func(operation v1.OperationName, req *http.Request, next ht.Client) (*http.Response, error) {
+		name := operation
+		if name == "" {
+			name = req.Method
+		}
+		ctx, span := tracer.Start(req.Context(), name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(AttributeOperation.String(operation), AttributeHTTPMethod.String(req.Method)),
+		)
+		defer span.End()
+		if m := applianceIDInPath.FindStringSubmatch(req.URL.Path); m != nil {
+			span.SetAttributes(AttributeApplianceID.String(m[1]))
+		}
+
+		res, err := next.Do(req.WithContext(ctx))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return res, err
+		}
+		span.SetAttributes(AttributeHTTPStatusCode.Int(res.StatusCode))
+		if res.StatusCode >= http.StatusBadRequest {
+			if body, ok := peekErrorResponse(res); ok {
+				span.SetAttributes(AttributeErrorSerial.String(body.Serial), AttributeErrorCode.String(body.ErrorCode))
+			}
+			span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+		}
+		return res, nil
+	})
+}
+
+type errorResponseBody struct {
+	Serial    string `json:"serial"`
+	ErrorCode string `json:"error_code"`
+}
+
+// peekErrorResponse レスポンスボディを読み戻せるようにしつつ、エラーレスポンスのserialとerror_codeを取り出す
+func peekErrorResponse(res *http.Response) (errorResponseBody, bool) {
+	var ret errorResponseBody
+	if res.Body == nil {
+		return ret, false
+	}
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		// 読み取れた分を返した後に同じエラーを返し、呼び出し元にエラーが伝わるようにする
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), errorReader{err}), res.Body}
+		return ret, false
+	}
+	res.Body.Close() //nolint:errcheck
+	res.Body = io.NopCloser(bytes.NewReader(b))
+	return ret, json.Unmarshal(b, &ret) == nil
+}
+
+// errorReader 常にerrを返すio.Reader
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// startSpan クライアントのTracerで複数のAPI呼び出しからなる処理のスパンを開始する
+func startSpan(ctx context.Context, client *Client, name string, applianceID string) (context.Context, trace.Span) {
+	var opts []trace.SpanStartOption
+	if applianceID != "" {
+		opts = append(opts, trace.WithAttributes(AttributeApplianceID.String(applianceID)))
+	}
+	tracer := client.tracer
+	if tracer == nil {
+		tracer = newTracer(nil)
+	}
+	return tracer.Start(ctx, name, opts...)
+}
+
+// endSpan errをスパンに記録して終了する
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
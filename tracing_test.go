@@ -0,0 +1,171 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	ht "github.com/ogen-go/ogen/http"
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTracerProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = tp.Shutdown(t.Context()) })
+	return tp, exporter
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	ret := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		ret[kv.Key] = kv.Value
+	}
+	return ret
+}
+
+func TestWithTracerProvider_APIError(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusNotFound, `{"is_fatal":true,"serial":"abc123","status":"404 Not Found","error_code":"not_found","error_msg":"対象が見つかりません。"}`
+	})
+	tp, exporter := newTracerProvider(t)
+
+	_, err := NewDatabaseOp(api.Client(WithTracerProvider(tp))).Read(t.Context(), "123")
+	assert.True(IsNotFound(err))
+
+	spans := exporter.GetSpans()
+	assert.Len(spans, 1)
+	assert.Equal(v1.GetDBOperation, spans[0].Name)
+	assert.Equal(codes.Error, spans[0].Status.Code)
+
+	attrs := spanAttributes(spans[0])
+	assert.Equal("123", attrs[AttributeApplianceID].AsString())
+	assert.Equal(int64(http.StatusNotFound), attrs[AttributeHTTPStatusCode].AsInt64())
+	assert.Equal("abc123", attrs[AttributeErrorSerial].AsString())
+	assert.Equal("not_found", attrs[AttributeErrorCode].AsString())
+
+	// ボディを読み戻せるようにしているため、エラーレスポンスの内容も取得できる
+	var apiErr *APIError
+	assert.ErrorAs(err, &apiErr)
+	assert.Equal("abc123", apiErr.Serial)
+}
+
+func TestWithTracerProvider_BodyReadError(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusNotFound, `{"is_fatal":true,"serial":"abc123","status":"404 Not Found","error_code":"not_found","error_msg":"対象が見つかりません。"}`
+	})
+	// ボディの途中で通信が切れたことにする
+	readErr := errors.New("connection reset")
+	cut := MiddlewareFunc(func(_ v1.OperationName, req *http.Request, next ht.Client) (*http.Response, error) {
+		res, err := next.Do(req)
+		if err != nil {
+			return res, err
+		}
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(io.LimitReader(res.Body, 10), &failingReader{err: readErr}), res.Body}
+		return res, nil
+	})
+	tp, exporter := newTracerProvider(t)
+
+	_, err := NewDatabaseOp(api.Client(WithTracerProvider(tp), WithMiddleware(cut))).Read(t.Context(), "123")
+	assert.ErrorIs(err, readErr)
+
+	spans := exporter.GetSpans()
+	assert.Len(spans, 1)
+	assert.Equal(codes.Error, spans[0].Status.Code)
+	assert.NotContains(spanAttributes(spans[0]), AttributeErrorSerial)
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestWithTracerProvider_Waiter(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		if n < 2 {
+			return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"migrating"}}`
+		}
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"available"}}`
+	})
+	tp, exporter := newTracerProvider(t)
+
+	_, err := NewDatabaseOp(api.Client(WithTracerProvider(tp))).WaitUntilAvailable(t.Context(), "123", fastWait)
+	assert.NoError(err)
+
+	spans := exporter.GetSpans()
+	assert.Len(spans, 3)
+	parent := spans[2]
+	assert.Equal("Database.WaitUntilAvailable", parent.Name)
+	assert.Equal("123", spanAttributes(parent)[AttributeApplianceID].AsString())
+	assert.Len(parent.Events, 2)
+	assert.Equal("poll", parent.Events[0].Name)
+	for _, span := range spans[:2] {
+		assert.Equal(v1.GetDBOperation, span.Name)
+		assert.Equal(parent.SpanContext.SpanID(), span.Parent.SpanID())
+		assert.Equal(parent.SpanContext.TraceID(), span.SpanContext.TraceID())
+	}
+}
+
+func TestWithoutTracerProvider(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"available"}}`
+	})
+
+	_, err := NewDatabaseOp(api.Client()).WaitUntilAvailable(t.Context(), "123", fastWait)
+	assert.NoError(err)
+}
+
+func TestWithTracerProvider_JobTracker(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/status", func(n int, _ *http.Request) (int, string) {
+		if n == 1 {
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[{"JobType":"Backup","JobStatus":"Running"}]}}}}`
+		}
+		return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"Jobs":[{"JobType":"Backup","JobStatus":"Done"}]}}}}`
+	})
+	tp, exporter := newTracerProvider(t)
+	metrics := &recordingMetrics{}
+
+	tracker := NewJobTracker(api.Client(WithTracerProvider(tp), WithMetrics(metrics)), fastWait, nil)
+	_, err := tracker.WaitForJobs(t.Context(), "123", nil)
+	assert.NoError(err)
+
+	spans := exporter.GetSpans()
+	assert.Len(spans, 3)
+	assert.Equal("Job.WaitForJobs", spans[2].Name)
+	assert.Len(spans[2].Events, 2)
+	assert.Equal(spans[2].SpanContext.SpanID(), spans[0].Parent.SpanID())
+	assert.Len(metrics.polls, 2)
+	assert.Len(metrics.requests, 2)
+}
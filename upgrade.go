@@ -0,0 +1,137 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+)
+
+// UpgradeOptions UpgradeAndWaitの設定
+type UpgradeOptions struct {
+	// Backup trueの場合、バージョンアップ前にバックアップを作成する
+	Backup bool
+	// Wait バックアップ作成とバージョンアップ完了の待機に使う設定
+	Wait *WaitOptions
+}
+
+// UpgradeStep UpgradeAndWaitの各ステップ
+type UpgradeStep string
+
+const (
+	UpgradeStepValidate UpgradeStep = "validate"
+	UpgradeStepBackup   UpgradeStep = "backup"
+	UpgradeStepUpgrade  UpgradeStep = "upgrade"
+	UpgradeStepWait     UpgradeStep = "wait"
+)
+
+// UpgradeStepResult UpgradeAndWaitの各ステップの実行結果
+type UpgradeStepResult struct {
+	Step       UpgradeStep
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Detail     string
+	Err        error
+}
+
+// UpgradeReport UpgradeAndWaitの実行結果
+type UpgradeReport struct {
+	FromVersion string
+	ToVersion   string
+	// Backup バージョンアップ前に作成したバックアップ。作成しなかった場合はnil
+	Backup *v1.NosqlBackup
+	Steps  []UpgradeStepResult
+}
+
+// UnsupportedVersionError 指定したバージョンが更新可能なバージョンに含まれないことを示すエラー
+type UnsupportedVersionError struct {
+	Version    string
+	Upgradable []string
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("version %q is not upgradable (upgradable versions: %v)", e.Version, e.Upgradable)
+}
+
+func (r *UpgradeReport) run(step UpgradeStep, fn func() (string, error)) error {
+	result := UpgradeStepResult{Step: step, StartedAt: time.Now()}
+	result.Detail, result.Err = fn()
+	result.FinishedAt = time.Now()
+	r.Steps = append(r.Steps, result)
+	return result.Err
+}
+
+// UpgradeAndWait 指定したバージョンが更新可能であることを確認してからバージョンアップし、
+// DatabaseVersionが指定したバージョンになるまで待機する。各ステップの結果はエラー時も含めてUpgradeReportに記録される
+func (op *instanceOp) UpgradeAndWait(ctx context.Context, version string, opts *UpgradeOptions) (_ *UpgradeReport, err error) {
+	ctx, span := startSpan(ctx, op.client, "Instance.UpgradeAndWait", op.dbId)
+	defer func() { endSpan(span, err) }()
+
+	if opts == nil {
+		opts = &UpgradeOptions{}
+	}
+	report := &UpgradeReport{ToVersion: version}
+
+	err = report.run(UpgradeStepValidate, func() (string, error) {
+		current, err := op.GetVersion(ctx)
+		if err != nil {
+			return "", err
+		}
+		report.FromVersion = current.DatabaseVersion
+
+		upgradable := make([]string, 0, len(current.UpgradableVersions))
+		for _, v := range current.UpgradableVersions {
+			upgradable = append(upgradable, v.Version)
+		}
+		if !slices.Contains(upgradable, version) {
+			return "", NewError("Instance.UpgradeAndWait", &UnsupportedVersionError{Version: version, Upgradable: upgradable})
+		}
+		return fmt.Sprintf("%s -> %s", current.DatabaseVersion, version), nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	if opts.Backup {
+		err := report.run(UpgradeStepBackup, func() (string, error) {
+			backup, err := NewBackupOp(op.client, op.dbId, op.options()...).CreateAndWait(ctx, opts.Wait)
+			if err != nil {
+				return "", err
+			}
+			report.Backup = backup
+			return backup.BackupId.String(), nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	err = report.run(UpgradeStepUpgrade, func() (string, error) {
+		return version, op.UpgradeVersion(ctx, version)
+	})
+	if err != nil {
+		return report, err
+	}
+
+	err = report.run(UpgradeStepWait, func() (string, error) {
+		databaseOp := NewDatabaseOp(op.client, op.options()...)
+		var state string
+		err := poll(ctx, metricsOf(op.client), "Instance.UpgradeAndWait", opts.Wait, func(ctx context.Context) (string, bool, error) {
+			status, err := databaseOp.GetStatus(ctx, op.dbId)
+			if err != nil {
+				return "", false, err
+			}
+			// UpgradeVersionは更新可能な最新のバージョンで、更新後も対象バージョンが残ることがあるため判定に使わない
+			current := status.DatabaseVersion.Value
+			state = "version=" + current
+			return state, current == version, nil
+		})
+		return state, err
+	})
+	return report, err
+}
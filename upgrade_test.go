@@ -0,0 +1,77 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	. "github.com/sacloud/nosql-api-go"
+	"github.com/stretchr/testify/require"
+)
+
+const versionJSON = `{"nosql":{"DatabaseVersion":"4.1.9","UpgradableVersions":[{"version":"4.1.10"}]},"is_ok":true}`
+
+func TestInstanceOp_UpgradeAndWait(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/nosql/version", func(int, *http.Request) (int, string) {
+		return http.StatusOK, versionJSON
+	})
+	api.Handle("GET /appliance/123/nosql/backup", func(n int, _ *http.Request) (int, string) {
+		if n == 1 {
+			return http.StatusOK, `{"nosql":{"backups":[]}}`
+		}
+		return http.StatusOK, `{"nosql":{"backups":[` + backupNew + `]}}`
+	})
+	api.Handle("POST /appliance/123/nosql/backup", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"is_ok":true}`
+	})
+	var body string
+	api.Handle("PUT /appliance/123/nosql/version", func(_ int, r *http.Request) (int, string) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		return http.StatusOK, `{"is_ok":true}`
+	})
+	api.Handle("GET /appliance/123/status", func(n int, _ *http.Request) (int, string) {
+		if n == 1 {
+			return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"DatabaseVersion":"4.1.9","UpgradeVersion":"4.1.10"}}}}`
+		}
+		// 4.1.10が更新可能な最新のバージョンなので、更新後もUpgradeVersionに残る
+		return http.StatusOK, `{"Appliance":{"ID":"123","SettingsResponse":{"Nosql":{"DatabaseVersion":"4.1.10","UpgradeVersion":"4.1.10"}}}}`
+	})
+
+	report, err := NewInstanceOp(api.Client(), "123", "tk1b").UpgradeAndWait(t.Context(), "4.1.10",
+		&UpgradeOptions{Backup: true, Wait: fastWait})
+	assert.NoError(err)
+	assert.Equal("4.1.9", report.FromVersion)
+	assert.Equal("4.1.10", report.ToVersion)
+	assert.NotNil(report.Backup)
+	assert.JSONEq(`{"nosql":{"version":"4.1.10"}}`, body)
+
+	var steps []UpgradeStep
+	for _, s := range report.Steps {
+		assert.NoError(s.Err)
+		steps = append(steps, s.Step)
+	}
+	assert.Equal([]UpgradeStep{UpgradeStepValidate, UpgradeStepBackup, UpgradeStepUpgrade, UpgradeStepWait}, steps)
+}
+
+func TestInstanceOp_UpgradeAndWait_Unsupported(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123/nosql/version", func(int, *http.Request) (int, string) {
+		return http.StatusOK, versionJSON
+	})
+
+	report, err := NewInstanceOp(api.Client(), "123", "tk1b").UpgradeAndWait(t.Context(), "5.0.0", nil)
+	var unsupported *UnsupportedVersionError
+	assert.ErrorAs(err, &unsupported)
+	assert.Equal([]string{"4.1.10"}, unsupported.Upgradable)
+	assert.Len(report.Steps, 1)
+	assert.Equal(0, api.Calls("PUT /appliance/123/nosql/version"))
+}
@@ -0,0 +1,133 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultWaitInterval 待機処理でのデフォルトのポーリング間隔
+	DefaultWaitInterval = 10 * time.Second
+
+	// DefaultWaitTimeout 待機処理のデフォルトのタイムアウト
+	DefaultWaitTimeout = 60 * time.Minute
+)
+
+// ErrWaitTimeout 待機処理がタイムアウトしたことを示すエラー
+var ErrWaitTimeout = errors.New("timed out while waiting")
+
+// WaitOptions 待機処理の設定。nilやゼロ値の項目にはデフォルト値が使われる
+type WaitOptions struct {
+	// Interval ポーリング間隔の初期値
+	Interval time.Duration
+	// MaxInterval バックオフで伸ばすポーリング間隔の上限。0の場合は上限なし
+	MaxInterval time.Duration
+	// Backoff ポーリング毎にポーリング間隔へ掛ける係数。1以下の場合は固定間隔
+	Backoff float64
+	// Timeout 待機全体のタイムアウト
+	Timeout time.Duration
+	// OnProgress ポーリング毎に呼び出されるコールバック
+	OnProgress func(WaitProgress)
+}
+
+// WaitProgress 待機処理の途中経過
+type WaitProgress struct {
+	// Attempt 何回目のポーリングか(1始まり)
+	Attempt int
+	// Elapsed 待機開始からの経過時間
+	Elapsed time.Duration
+	// State ポーリングで観測した状態
+	State string
+}
+
+// ApplianceFailedError アプライアンスのAvailabilityがfailedになったことを示すエラー
+type ApplianceFailedError struct {
+	ID string
+}
+
+func (e *ApplianceFailedError) Error() string {
+	return fmt.Sprintf("appliance %s became failed", e.ID)
+}
+
+func (o *WaitOptions) normalize() WaitOptions {
+	var ret WaitOptions
+	if o != nil {
+		ret = *o
+	}
+	if ret.Interval <= 0 {
+		ret.Interval = DefaultWaitInterval
+	}
+	if ret.Timeout <= 0 {
+		ret.Timeout = DefaultWaitTimeout
+	}
+	return ret
+}
+
+func (o *WaitOptions) nextInterval(cur time.Duration) time.Duration {
+	if o.Backoff <= 1 {
+		return cur
+	}
+	next := time.Duration(float64(cur) * o.Backoff)
+	if o.MaxInterval > 0 && next > o.MaxInterval {
+		next = o.MaxInterval
+	}
+	return next
+}
+
+// poll checkがdoneを返すまで、optsに従ってcheckを繰り返し呼び出す。
+// checkがエラーを返した場合はそのまま返す。ポーリング毎にmetricsへ記録する
+func poll(ctx context.Context, metrics Metrics, name string, opts *WaitOptions, check func(ctx context.Context) (state string, done bool, err error)) error {
+	o := opts.normalize()
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	interval := o.Interval
+	state := ""
+	for attempt := 1; ; attempt++ {
+		s, done, err := check(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return waitAborted(parent, name, o.Timeout, state)
+			}
+			return err
+		}
+		state = s
+		trace.SpanFromContext(ctx).AddEvent("poll", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("state", state),
+		))
+		metrics.ObservePoll(PollObservation{Name: name, Attempt: attempt, State: state})
+		if o.OnProgress != nil {
+			o.OnProgress(WaitProgress{Attempt: attempt, Elapsed: time.Since(start), State: state})
+		}
+		if done {
+			return nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return waitAborted(parent, name, o.Timeout, state)
+		case <-timer.C:
+		}
+		interval = o.nextInterval(interval)
+	}
+}
+
+func waitAborted(parent context.Context, name string, timeout time.Duration, state string) error {
+	if err := parent.Err(); err != nil {
+		return NewError(name, err)
+	}
+	return NewError(name, fmt.Errorf("%w after %s (last state: %q)", ErrWaitTimeout, timeout, state))
+}
@@ -0,0 +1,149 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/sacloud/nosql-api-go"
+	v1 "github.com/sacloud/nosql-api-go/apis/v1"
+	"github.com/sacloud/saclient-go"
+	"github.com/stretchr/testify/require"
+)
+
+var fastWait = &WaitOptions{Interval: time.Millisecond, Timeout: 5 * time.Second}
+
+func TestDatabaseOp_WaitUntilAvailable(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(n int, _ *http.Request) (int, string) {
+		if n < 3 {
+			return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"migrating"}}`
+		}
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"available"}}`
+	})
+
+	var progress []WaitProgress
+	opts := *fastWait
+	opts.OnProgress = func(p WaitProgress) { progress = append(progress, p) }
+
+	res, err := NewDatabaseOp(api.Client()).WaitUntilAvailable(t.Context(), "123", &opts)
+	assert.NoError(err)
+	assert.Equal(v1.AvailabilityAvailable, res.Availability.Value)
+	assert.Len(progress, 3)
+	assert.Equal("migrating", progress[0].State)
+	assert.Equal(3, progress[2].Attempt)
+	assert.Equal("available", progress[2].State)
+}
+
+func TestDatabaseOp_WaitUntilAvailable_Failed(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"failed"}}`
+	})
+
+	_, err := NewDatabaseOp(api.Client()).WaitUntilAvailable(t.Context(), "123", fastWait)
+	var failed *ApplianceFailedError
+	assert.ErrorAs(err, &failed)
+	assert.Equal("123", failed.ID)
+}
+
+func TestDatabaseOp_WaitUntilAvailable_Timeout(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"migrating"}}`
+	})
+
+	_, err := NewDatabaseOp(api.Client()).WaitUntilAvailable(t.Context(), "123",
+		&WaitOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond})
+	assert.ErrorIs(err, ErrWaitTimeout)
+}
+
+func TestDatabaseOp_WaitUntilAvailable_Canceled(t *testing.T) {
+	assert := require.New(t)
+
+	api := newFakeAPI(t)
+	api.Handle("GET /appliance/123", func(int, *http.Request) (int, string) {
+		return http.StatusOK, `{"Appliance":{"ID":"123","Availability":"migrating"}}`
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	opts := *fastWait
+	opts.OnProgress = func(WaitProgress) { cancel() }
+
+	_, err := NewDatabaseOp(api.Client()).WaitUntilAvailable(ctx, "123", &opts)
+	assert.ErrorIs(err, context.Canceled)
+	assert.False(errors.Is(err, ErrWaitTimeout))
+}
+
+type fakeHandler func(n int, r *http.Request) (status int, body string)
+
+// fakeAPI "METHOD /path"毎に登録したハンドラでレスポンスを返すテスト用のAPIサーバ。
+// ハンドラには同じキーへの何回目の呼び出しか(1始まり)が渡される
+type fakeAPI struct {
+	t        *testing.T
+	mu       sync.Mutex
+	handlers map[string]fakeHandler
+	calls    map[string]int
+	server   *httptest.Server
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	t.Helper()
+	api := &fakeAPI{t: t, handlers: map[string]fakeHandler{}, calls: map[string]int{}}
+	api.server = httptest.NewServer(http.HandlerFunc(api.serve))
+	t.Cleanup(api.server.Close)
+	return api
+}
+
+func (f *fakeAPI) Handle(key string, h fakeHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[key] = h
+}
+
+func (f *fakeAPI) Calls(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[key]
+}
+
+func (f *fakeAPI) Client(opts ...ClientOption) *Client {
+	f.t.Helper()
+	var theClient saclient.Client
+	require.NoError(f.t, theClient.SetEnviron([]string{"SAKURA_RATE_LIMIT=1000"}))
+	client, err := NewClientWithAPIRootURL(&theClient, f.server.URL, opts...)
+	require.NoError(f.t, err)
+	return client
+}
+
+func (f *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + r.URL.Path
+	f.mu.Lock()
+	f.calls[key]++
+	n := f.calls[key]
+	h, ok := f.handlers[key]
+	f.mu.Unlock()
+
+	if !ok {
+		f.t.Errorf("unexpected request: %s", key)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	status, body := h(n, r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
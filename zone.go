@@ -0,0 +1,89 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sacloud/saclient-go"
+)
+
+// APIRootURLTemplate ゾーン毎のAPIルートURLのテンプレート。{zone}をゾーン名に置き換える
+const APIRootURLTemplate = "https://secure.sakura.ad.jp/cloud/zone/{zone}/api/cloud/1.1"
+
+var zones = []string{"is1a", "is1b", "tk1a", "tk1b", "tk1v"}
+
+var zoneInURL = regexp.MustCompile(`/zone/([a-z0-9]+)/`)
+
+// Zones NoSQLを利用できるゾーンの一覧を返す
+func Zones() []string {
+	return slices.Clone(zones)
+}
+
+// ValidateZone ゾーン名がZonesに含まれるか検証する
+func ValidateZone(zone string) error {
+	if !slices.Contains(zones, zone) {
+		return NewError(fmt.Sprintf("unknown zone %q (valid zones: %s)", zone, strings.Join(zones, ", ")), nil)
+	}
+	return nil
+}
+
+// APIRootURLForZone APIRootURLTemplateからゾーンのAPIルートURLを返す
+func APIRootURLForZone(zone string) (string, error) {
+	if err := ValidateZone(zone); err != nil {
+		return "", err
+	}
+	return expandZone(APIRootURLTemplate, zone), nil
+}
+
+func expandZone(template, zone string) string {
+	return strings.ReplaceAll(template, "{zone}", zone)
+}
+
+// NewClientForZone ゾーンのAPIルートURLを使うクライアントを作成する。
+// プロファイルなどでエンドポイントが指定されている場合はそちらを使い、{zone}が含まれていればゾーン名に置き換える。
+// 置き換え後のエンドポイントに別のゾーン名が含まれる場合はエラーを返す
+func NewClientForZone(client saclient.ClientAPI, zone string, opts ...ClientOption) (*Client, error) {
+	endpoint, err := APIRootURLForZone(zone)
+	if err != nil {
+		return nil, err
+	}
+	endpointConfig, err := client.EndpointConfig()
+	if err != nil {
+		return nil, NewError("unable to load endpoint configuration", err)
+	}
+	if ep, ok := endpointConfig.Endpoints[ServiceKey]; ok && ep != "" {
+		endpoint = expandZone(ep, zone)
+	}
+	if z := zoneFromURL(endpoint); z != "" && z != zone {
+		return nil, NewError(fmt.Sprintf("endpoint %s is for zone %q, not %q", endpoint, z, zone), nil)
+	}
+
+	return newClient(client, endpoint, zone, opts)
+}
+
+// zoneFromURL APIルートURLに含まれるゾーン名を返す
+func zoneFromURL(apiRootURL string) string {
+	if m := zoneInURL.FindStringSubmatch(apiRootURL); m != nil && ValidateZone(m[1]) == nil {
+		return m[1]
+	}
+	return ""
+}
+
+// resolveZone 明示されたゾーンとクライアントのゾーンを突き合わせる
+func resolveZone(client *Client, zone string) (string, error) {
+	clientZone := client.Zone()
+	switch {
+	case zone == "" && clientZone == "":
+		return "", fmt.Errorf("zone is unknown: specify the zone or create the client with NewClientForZone")
+	case zone == "":
+		return clientZone, nil
+	case clientZone != "" && zone != clientZone:
+		return "", fmt.Errorf("zone %q does not match the zone of the client %q", zone, clientZone)
+	}
+	return zone, nil
+}
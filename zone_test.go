@@ -0,0 +1,99 @@
+// Copyright 2016-2025 The terraform-provider-sakura Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package nosql_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	. "github.com/sacloud/nosql-api-go"
+	"github.com/sacloud/saclient-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIRootURLForZone(t *testing.T) {
+	assert := require.New(t)
+
+	for _, zone := range Zones() {
+		url, err := APIRootURLForZone(zone)
+		assert.NoError(err)
+		assert.Equal("https://secure.sakura.ad.jp/cloud/zone/"+zone+"/api/cloud/1.1", url)
+	}
+	_, err := APIRootURLForZone("tk2a")
+	assert.Error(err)
+}
+
+func TestNewClientForZone(t *testing.T) {
+	assert := require.New(t)
+
+	var theClient saclient.Client
+	_, err := NewClientForZone(&theClient, "us1a")
+	assert.Error(err)
+
+	client, err := NewClientForZone(&theClient, "is1b")
+	assert.NoError(err)
+	assert.Equal("is1b", client.Zone())
+
+	// APIルートURLからゾーンを判定する
+	client, err = NewClient(&theClient)
+	assert.NoError(err)
+	assert.Equal("tk1b", client.Zone())
+	client, err = NewClientWithAPIRootURL(&theClient, "https://example.com/api")
+	assert.NoError(err)
+	assert.Empty(client.Zone())
+}
+
+func TestNewClientForZone_EndpointMismatch(t *testing.T) {
+	assert := require.New(t)
+
+	// {zone}を含まないエンドポイントが別のゾーンを指している
+	var theClient saclient.Client
+	assert.NoError(theClient.SetEnviron([]string{"SAKURA_ENDPOINTS_NOSQL=https://secure.sakura.ad.jp/cloud/zone/tk1b/api/cloud/1.1"}))
+	_, err := NewClientForZone(&theClient, "is1a")
+	assert.ErrorContains(err, `endpoint https://secure.sakura.ad.jp/cloud/zone/tk1b/api/cloud/1.1 is for zone "tk1b", not "is1a"`)
+
+	_, err = NewClientForZone(&theClient, "tk1b")
+	assert.NoError(err)
+}
+
+func TestNewClientForZone_Ops(t *testing.T) {
+	assert := require.New(t)
+
+	var mu sync.Mutex
+	var paths, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"Appliance":{"ID":"123","Availability":"migrating"},"Success":true,"is_ok":true}`))
+	}))
+	defer server.Close()
+
+	var theClient saclient.Client
+	assert.NoError(theClient.SetEnviron([]string{"SAKURA_RATE_LIMIT=1000", "SAKURA_ENDPOINTS_NOSQL=" + server.URL + "/zone/{zone}"}))
+	client, err := NewClientForZone(&theClient, "is1a")
+	assert.NoError(err)
+
+	request := clusterApplianceRequest("nosql")
+	request.Remark.Nosql.Zone = ""
+	_, err = NewDatabaseOp(client).Create(t.Context(), Plan100GB, request)
+	assert.NoError(err)
+	assert.Equal([]string{"POST /zone/is1a/appliance"}, paths)
+	assert.Contains(bodies[0], `"Zone":"is1a"`)
+
+	request.Remark.Nosql.Zone = "tk1b"
+	_, err = NewDatabaseOp(client).Create(t.Context(), Plan100GB, request)
+	assert.Error(err)
+
+	_, err = NewInstanceOp(client, "123", "tk1b").AddNodes(t.Context(), Plan100GB, clusterApplianceRequest("nosql-add"))
+	assert.Error(err)
+	assert.Len(paths, 1)
+}